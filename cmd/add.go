@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/qingchencloud/cftunnel/internal/authproxy"
@@ -13,22 +15,86 @@ import (
 )
 
 var addDomain string
-var addAuth string
+var addPath string
+var addService string
+var addAuth []string
+var addAllowIPs, addDenyIPs []string
+var addOrigin originFlags
+var addOverwrite, addAdopt bool
 
 func init() {
 	addCmd.Flags().StringVar(&addDomain, "domain", "", "完整域名 (如 webhook.example.com)")
 	addCmd.MarkFlagRequired("domain")
-	addCmd.Flags().StringVar(&addAuth, "auth", "", "启用密码保护 (格式: 用户名:密码)")
+	addCmd.Flags().StringVar(&addService, "service", "", "服务地址，替代端口参数 (如 https://localhost:8443、ssh:22、unix:/run/app.sock、http_status:404)")
+	addCmd.Flags().StringVar(&addPath, "path", "", "路径正则 (如 ^/api/)，同一域名可添加多条不同路径的路由")
+	addCmd.Flags().StringArrayVar(&addAuth, "auth", nil, "启用密码保护 (格式: 用户名:密码，可重复指定多个用户)")
+	addCmd.Flags().StringSliceVar(&addAllowIPs, "allow-ip", nil, "只允许这些 IP/CIDR 访问，可重复指定")
+	addCmd.Flags().StringSliceVar(&addDenyIPs, "deny-ip", nil, "拒绝这些 IP/CIDR 访问，可重复指定")
+	addCmd.Flags().BoolVar(&addOverwrite, "overwrite", false, "域名已有 A/AAAA/CNAME 记录时替换，原记录备份到配置中，remove 时恢复")
+	addCmd.Flags().BoolVar(&addAdopt, "adopt", false, "域名已有指向本隧道的 CNAME 时直接接管")
+	addOrigin.register(addCmd)
 	rootCmd.AddCommand(addCmd)
 }
 
-// pushIngress 推送当前所有路由的 ingress 配置到远端
+// pushIngress 推送当前所有路由的 ingress 配置到远端，与远端一致时跳过
 func pushIngress(client *cfapi.Client, ctx context.Context, cfg *config.Config) error {
+	changed, err := ensureProxyPorts(cfg)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+	}
+
+	rules := ingressRules(cfg)
+	if remote, err := client.GetIngressConfig(ctx, cfg.Tunnel.ID); err == nil && slices.Equal(remote, rules) {
+		return nil
+	}
+	return client.PushIngressConfig(ctx, cfg.Tunnel.ID, rules)
+}
+
+// ingressRules 根据本地路由生成应下发的 ingress 规则（已排序）
+func ingressRules(cfg *config.Config) []cfapi.IngressRule {
 	var rules []cfapi.IngressRule
 	for _, r := range cfg.Routes {
-		rules = append(rules, cfapi.IngressRule{Hostname: r.Hostname, Service: r.Service})
+		rules = append(rules, cfapi.IngressRule{Hostname: r.Hostname, Path: r.Path, Service: ingressService(r), Origin: ingressOrigin(r)})
 	}
-	return client.PushIngressConfig(ctx, cfg.Tunnel.ID, rules)
+	orderIngress(rules)
+	return rules
+}
+
+// orderIngress 按匹配精确度排序：cloudflared 按顺序取第一条匹配的规则。
+// 精确域名排在通配域名（*.example.com）之前，通配域名后缀越长越靠前；
+// 同一域名下带路径的规则排在不带路径的规则之前，路径越长越靠前
+func orderIngress(rules []cfapi.IngressRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		aw, bw := strings.HasPrefix(a.Hostname, "*"), strings.HasPrefix(b.Hostname, "*")
+		if aw != bw {
+			return !aw
+		}
+		ah, bh := strings.ToLower(a.Hostname), strings.ToLower(b.Hostname)
+		if ah != bh {
+			if aw && len(ah) != len(bh) {
+				return len(ah) > len(bh)
+			}
+			return ah < bh
+		}
+		if (a.Path == "") != (b.Path == "") {
+			return a.Path != ""
+		}
+		return len(a.Path) > len(b.Path)
+	})
+}
+
+// pathSuffix 用于展示路由的路径规则
+func pathSuffix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " [" + path + "]"
 }
 
 // findZoneForDomain 通过遍历账户 Zone 列表匹配域名（支持多级 TLD）
@@ -46,12 +112,44 @@ func findZoneForDomain(client *cfapi.Client, ctx context.Context, domain string)
 }
 
 var addCmd = &cobra.Command{
-	Use:   "add <名称> <端口>",
+	Use:   "add <名称> [端口]",
 	Short: "添加路由（自动创建 CNAME + 更新 ingress）",
-	Args:  cobra.ExactArgs(2),
+	Long: `添加路由，服务可以是本地端口（HTTP），也可以通过 --service 指定其他协议。
+
+--service 支持:
+  https://localhost:8443        完整 URL（http、https、ssh、rdp、tcp、smb）
+  ssh:22 / rdp:3389 / tcp:5432  协议简写，指向本机端口
+  unix:/run/app.sock            unix socket（unix+tls: 为 HTTPS）
+  http_status:404               直接返回状态码
+
+示例:
+  cftunnel add web 3000 --domain web.example.com
+  cftunnel add bastion --service ssh:22 --domain ssh.example.com
+  cftunnel add web 3000 --domain www.example.com --overwrite`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		name, port := args[0], args[1]
-		service := "http://localhost:" + port
+		name := args[0]
+		if addOverwrite && addAdopt {
+			return fmt.Errorf("--overwrite 与 --adopt 只能指定一个")
+		}
+		var spec string
+		switch {
+		case len(args) == 2 && addService != "":
+			return fmt.Errorf("端口参数与 --service 只能指定一个")
+		case len(args) == 2:
+			spec = args[1]
+		case addService != "":
+			spec = addService
+		default:
+			return fmt.Errorf("请指定端口或 --service")
+		}
+		service, err := parseService(spec)
+		if err != nil {
+			return err
+		}
+		if !httpService(service) && (len(addAuth) > 0 || len(addAllowIPs) > 0 || len(addDenyIPs) > 0) {
+			return fmt.Errorf("--auth、--allow-ip、--deny-ip 只能用于 HTTP 服务")
+		}
 
 		cfg, err := config.Load()
 		if err != nil {
@@ -63,60 +161,84 @@ var addCmd = &cobra.Command{
 		if cfg.FindRoute(name) != nil {
 			return fmt.Errorf("路由 %s 已存在", name)
 		}
+		if _, err := authproxy.ParsePrefixes(append(addAllowIPs, addDenyIPs...)); err != nil {
+			return err
+		}
+		if addPath != "" {
+			if _, err := regexp.Compile(addPath); err != nil {
+				return fmt.Errorf("--path 不是有效的正则表达式: %w", err)
+			}
+		}
+		origin, err := addOrigin.apply(cmd, nil)
+		if err != nil {
+			return err
+		}
+		for _, r := range cfg.Routes {
+			if strings.EqualFold(r.Hostname, addDomain) && r.Path == addPath {
+				return fmt.Errorf("域名 %s 的路径 %q 已被路由 %s 使用", addDomain, addPath, r.Name)
+			}
+		}
 
 		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
 		ctx := context.Background()
-
-		// 查找域名对应的 Zone（支持多级 TLD）
-		zone, err := findZoneForDomain(client, ctx, addDomain)
+		t, err := beginTxn(client, ctx, "add", name, cfg)
 		if err != nil {
 			return err
 		}
 
-		// 创建 CNAME
-		target := cfg.Tunnel.ID + ".cfargotunnel.com"
-		fmt.Printf("正在创建 DNS 记录 %s → %s\n", addDomain, target)
-		recordID, err := client.CreateCNAME(ctx, zone.ID, addDomain, target)
-		if err != nil {
-			return err
+		var zoneID, recordID string
+		var prevDNS []config.DNSBackup
+		var steps []config.JournalStep
+		if existing := cfg.FindRouteByHostname(addDomain); existing != nil {
+			// 同一域名已有路由，共用已有的 DNS 记录
+			zoneID, recordID = existing.ZoneID, existing.DNSRecordID
+			fmt.Printf("域名 %s 已由路由 %s 创建 DNS 记录，直接复用\n", addDomain, existing.Name)
+		} else {
+			// 查找域名对应的 Zone（支持多级 TLD）
+			zone, err := findZoneForDomain(client, ctx, addDomain)
+			if err != nil {
+				return err
+			}
+
+			// 创建 CNAME，域名已有记录时按 --adopt / --overwrite 处理
+			zoneID = zone.ID
+			target := cfg.Tunnel.ID + ".cfargotunnel.com"
+			recordID, prevDNS, steps, err = planClaim(client, ctx, zone.ID, addDomain, target, addAdopt, addOverwrite)
+			if err != nil {
+				return err
+			}
 		}
 
 		// 构建路由配置
 		route := config.RouteConfig{
 			Name:        name,
 			Hostname:    addDomain,
+			Path:        addPath,
 			Service:     service,
-			ZoneID:      zone.ID,
+			ZoneID:      zoneID,
 			DNSRecordID: recordID,
+			AllowIPs:    addAllowIPs,
+			DenyIPs:     addDenyIPs,
+			Origin:      origin,
+			PrevDNS:     prevDNS,
 		}
 
 		// 如果指定了 --auth，填充鉴权配置
-		if addAuth != "" {
-			user, pass, err := parseAuth(addAuth)
+		if len(addAuth) > 0 {
+			route.Auth, err = newRouteAuth(addAuth)
 			if err != nil {
 				return err
 			}
-			route.Auth = &config.AuthProxy{
-				Username:   user,
-				Password:   pass,
-				SigningKey:  hex.EncodeToString(authproxy.RandomKey()),
-			}
 			fmt.Printf("已启用密码保护: %s\n", addDomain)
 		}
 
-		// 保存路由
+		// 创建 DNS 记录、保存路由并推送 ingress，任何一步失败都会回滚
 		cfg.Routes = append(cfg.Routes, route)
-		if err := cfg.Save(); err != nil {
+		if err := t.commit(cfg, append(steps, saveConfigStep, pushIngressStep)...); err != nil {
 			return err
 		}
 
-		// 推送 ingress 配置到远端
-		fmt.Println("正在同步 ingress 配置...")
-		if err := pushIngress(client, ctx, cfg); err != nil {
-			return fmt.Errorf("推送 ingress 失败: %w（DNS 记录已创建，请排查后重试 add 或手动删除 DNS 记录）", err)
-		}
-
-		fmt.Printf("路由已添加: %s → %s (%s)\n", addDomain, service, name)
+		fmt.Printf("路由已添加: %s%s → %s (%s)\n", addDomain, pathSuffix(addPath), service, name)
 		return nil
 	},
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go/v6/shared"
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+}
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <隧道名称|ID>",
+	Short: "导入已有的 Cloudflare Tunnel（含 ingress 路由与 DNS 记录）",
+	Long: `将在 Cloudflare 控制台或其他工具中创建的隧道纳入 cftunnel 管理:
+读取隧道的远端 ingress 规则生成路由，并在账户下所有 Zone 中查找指向该隧道的 CNAME 记录。
+
+示例:
+  cftunnel adopt my-tunnel
+  cftunnel adopt 6ff42ae2-765d-4adf-8112-31c55c1551ef`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if cfg.Auth.APIToken == "" {
+			return fmt.Errorf("请先运行 cftunnel init 配置认证信息")
+		}
+		if cfg.Tunnel.ID != "" {
+			return fmt.Errorf("已存在隧道 %s (%s)，如需导入其他隧道请先 cftunnel destroy 或 cftunnel reset", cfg.Tunnel.Name, cfg.Tunnel.ID)
+		}
+
+		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
+		ctx := context.Background()
+
+		tunnel, err := findTunnel(client, ctx, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("找到隧道: %s (%s)\n", tunnel.Name, tunnel.ID)
+
+		token, err := client.GetTunnelToken(ctx, tunnel.ID)
+		if err != nil {
+			return err
+		}
+		rules, err := client.GetIngressConfig(ctx, tunnel.ID)
+		if err != nil {
+			// 本地配置文件管理的隧道没有远端 ingress，只导入隧道本身
+			fmt.Printf("警告: %v，将只导入隧道，路由需要用 cftunnel add 重新添加\n", err)
+		}
+
+		// 在所有 Zone 中查找指向该隧道的 CNAME
+		target := tunnel.ID + ".cfargotunnel.com"
+		records := make(map[string]config.RouteConfig) // 小写域名 → ZoneID/DNSRecordID
+		if len(rules) > 0 {
+			zones, err := client.ListZones(ctx)
+			if err != nil {
+				return err
+			}
+			for _, z := range zones {
+				cnames, err := client.ListCNAMEsTo(ctx, z.ID, target)
+				if err != nil {
+					return err
+				}
+				for _, rec := range cnames {
+					records[strings.ToLower(rec.Name)] = config.RouteConfig{ZoneID: z.ID, DNSRecordID: rec.ID}
+				}
+			}
+		}
+
+		var routes []config.RouteConfig
+		used := make(map[string]bool)
+		for _, rule := range rules {
+			r := config.RouteConfig{
+				Name:     routeNameFor(rule.Hostname, used),
+				Hostname: rule.Hostname,
+				Path:     rule.Path,
+				Service:  rule.Service,
+				Origin:   routeOrigin(rule.Origin),
+			}
+			if rec, ok := records[strings.ToLower(rule.Hostname)]; ok {
+				r.ZoneID, r.DNSRecordID = rec.ZoneID, rec.DNSRecordID
+				fmt.Printf("  %s: %s%s → %s\n", r.Name, r.Hostname, pathSuffix(r.Path), r.Service)
+			} else {
+				fmt.Printf("  %s: %s%s → %s（未找到 CNAME 记录，可运行 cftunnel sync 修复）\n", r.Name, r.Hostname, pathSuffix(r.Path), r.Service)
+			}
+			routes = append(routes, r)
+		}
+
+		cfg.Tunnel = config.TunnelConfig{ID: tunnel.ID, Name: tunnel.Name, Token: token}
+		cfg.Routes = routes
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 隧道 %s 已导入，共 %d 条路由\n", tunnel.Name, len(routes))
+		fmt.Println("\n下一步: cftunnel up")
+		return nil
+	},
+}
+
+// findTunnel 按 ID 或名称查找账户下未删除的隧道
+func findTunnel(client *cfapi.Client, ctx context.Context, nameOrID string) (*shared.CloudflareTunnel, error) {
+	tunnels, err := client.ListTunnels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []shared.CloudflareTunnel
+	for _, t := range tunnels {
+		if !t.DeletedAt.IsZero() {
+			continue
+		}
+		if strings.EqualFold(t.ID, nameOrID) {
+			return &t, nil
+		}
+		if t.Name == nameOrID {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("未找到隧道 %s", nameOrID)
+	case 1:
+		return &matches[0], nil
+	}
+	var ids []string
+	for _, t := range matches {
+		ids = append(ids, t.ID)
+	}
+	return nil, fmt.Errorf("有多个名为 %s 的隧道，请改用 ID: %s", nameOrID, strings.Join(ids, ", "))
+}
+
+// routeNameFor 以域名的第一段作为路由名，重名时追加序号
+func routeNameFor(hostname string, used map[string]bool) string {
+	base, _, _ := strings.Cut(hostname, ".")
+	base = strings.TrimPrefix(base, "*")
+	if base == "" {
+		base = "route"
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	used[name] = true
+	return name
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "路由访问保护管理（用户、会话、IP 规则、密钥）",
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+}
+
+// newAuthUser 将 "用户名:密码" 解析为只保存哈希的用户
+func newAuthUser(spec string) (config.AuthUser, error) {
+	user, pass, err := parseAuth(spec)
+	if err != nil {
+		return config.AuthUser{}, err
+	}
+	hash, err := authproxy.HashPassword(pass)
+	if err != nil {
+		return config.AuthUser{}, err
+	}
+	return config.AuthUser{Username: user, PasswordHash: hash}, nil
+}
+
+// newRouteAuth 为路由生成鉴权配置（含随机签名密钥）
+func newRouteAuth(specs []string) (*config.AuthProxy, error) {
+	a := &config.AuthProxy{SigningKey: hex.EncodeToString(authproxy.RandomKey())}
+	for _, spec := range specs {
+		u, err := newAuthUser(spec)
+		if err != nil {
+			return nil, err
+		}
+		if a.FindUser(u.Username) != nil {
+			return nil, fmt.Errorf("用户 %s 重复", u.Username)
+		}
+		a.Users = append(a.Users, u)
+	}
+	return a, nil
+}
+
+// migrateLegacyAuth 将旧版明文单用户转换为哈希用户
+func migrateLegacyAuth(a *config.AuthProxy) error {
+	if a.Username == "" {
+		return nil
+	}
+	if a.FindUser(a.Username) == nil {
+		hash, err := authproxy.HashPassword(a.Password)
+		if err != nil {
+			return err
+		}
+		a.Users = append(a.Users, config.AuthUser{Username: a.Username, PasswordHash: hash})
+	}
+	a.Username, a.Password = "", ""
+	return nil
+}
+
+// proxyUsers 将路由鉴权配置转换为代理用户列表，旧版明文单用户需先经 migrateLegacyAuth 迁移
+func proxyUsers(a *config.AuthProxy) ([]authproxy.User, error) {
+	if a.Username != "" {
+		return nil, fmt.Errorf("旧版明文密码尚未迁移")
+	}
+	var users []authproxy.User
+	for _, u := range a.Users {
+		users = append(users, authproxy.User{Username: u.Username, PasswordHash: u.PasswordHash, TOTPSecret: u.TOTPSecret})
+	}
+	return users, nil
+}
+
+// hasAccessRules 路由是否配置了密码保护或 IP 规则
+func hasAccessRules(r config.RouteConfig) bool {
+	return r.Auth != nil || len(r.AllowIPs) > 0 || len(r.DenyIPs) > 0
+}
+
+// needsProxy 路由是否需要经过鉴权代理，非 HTTP 服务（SSH、RDP 等）无法代理
+func needsProxy(r config.RouteConfig) bool {
+	return hasAccessRules(r) && httpService(r.Service)
+}
+
+// requireHTTPService 密码保护与 IP 规则只能用于 HTTP 服务
+func requireHTTPService(r *config.RouteConfig) error {
+	if !httpService(r.Service) {
+		return fmt.Errorf("路由 %s 的服务 %s 不是 HTTP 服务，无法启用密码保护或 IP 规则", r.Name, r.Service)
+	}
+	return nil
+}
+
+// applyUpstream 将路由的 service 与源站连接选项作为鉴权代理的上游
+func applyUpstream(pc *authproxy.Config, r config.RouteConfig) {
+	pc.Upstream = r.Service
+	if o := r.Origin; o != nil {
+		pc.NoTLSVerify = o.NoTLSVerify
+		pc.CAFile = o.CAPool
+		pc.ServerName = o.OriginServerName
+		pc.HostHeader = o.HTTPHostHeader
+		pc.HTTP2 = o.HTTP2Origin
+	}
+}
+
+// identityHeader 解析身份头配置：留空使用默认值，- 表示不发送
+func identityHeader(name, def string) string {
+	switch name {
+	case "":
+		return def
+	case "-":
+		return ""
+	}
+	return name
+}
+
+// proxyConfig 根据路由的鉴权配置与 IP 规则构建鉴权代理配置
+func proxyConfig(r config.RouteConfig) (authproxy.Config, error) {
+	if r.Auth == nil {
+		// 仅有 IP 规则的路由：不需要登录，代理只做访问控制
+		pc := authproxy.Config{
+			Mode:       authproxy.ModeNone,
+			Hostname:   r.Hostname,
+			SigningKey: authproxy.RandomKey(),
+			AllowIPs:   r.AllowIPs,
+			DenyIPs:    r.DenyIPs,
+		}
+		applyUpstream(&pc, r)
+		return pc, nil
+	}
+
+	a := r.Auth
+	sigKey, err := hex.DecodeString(a.SigningKey)
+	if err != nil {
+		return authproxy.Config{}, fmt.Errorf("signing_key 无效: %w", err)
+	}
+	pc := authproxy.Config{
+		Mode:       a.Mode,
+		Hostname:   r.Hostname,
+		SigningKey: sigKey,
+		CookieTTL:  time.Duration(a.CookieTTLOrDefault()) * time.Second,
+
+		PublicPaths:          a.PublicPaths,
+		PublicWebSocketPaths: a.PublicWebSocketPaths,
+		SessionFile:          config.SessionPath(r.Name),
+		AllowIPs:             r.AllowIPs,
+		DenyIPs:              r.DenyIPs,
+		APIKeyHeader:         a.APIKeyHeader,
+	}
+	applyUpstream(&pc, r)
+	pc.Route = r.Name
+	if lp := a.LoginPage; lp != nil {
+		pc.Branding = &authproxy.LoginBranding{Template: lp.Template, Title: lp.Title, Logo: lp.Logo}
+	}
+	if h := a.IdentityHeaders; h != nil {
+		pc.IdentityHeaders = &authproxy.IdentityHeaders{
+			User:   identityHeader(h.User, authproxy.DefaultIdentityHeaders.User),
+			Email:  identityHeader(h.Email, authproxy.DefaultIdentityHeaders.Email),
+			Groups: identityHeader(h.Groups, authproxy.DefaultIdentityHeaders.Groups),
+		}
+	}
+	for _, k := range a.RetiredKeys {
+		key, err := hex.DecodeString(k.Key)
+		if err != nil {
+			return authproxy.Config{}, fmt.Errorf("retired_keys 中的密钥无效: %w", err)
+		}
+		if time.Now().Before(k.Expires) {
+			pc.RetiredKeys = append(pc.RetiredKeys, authproxy.RetiredKey{Key: key, Expires: k.Expires})
+		}
+	}
+	for _, k := range a.APIKeys {
+		pc.APIKeys = append(pc.APIKeys, authproxy.APIKey{Name: k.Name, Hash: k.Hash})
+	}
+
+	switch a.Mode {
+	case "", authproxy.ModePassword:
+		pc.Users, err = proxyUsers(a)
+		if err != nil {
+			return authproxy.Config{}, err
+		}
+		if len(pc.Users) == 0 && len(pc.APIKeys) == 0 {
+			return authproxy.Config{}, fmt.Errorf("未配置任何用户或 API Key")
+		}
+	case authproxy.ModeOIDC:
+		if a.OIDC == nil {
+			return authproxy.Config{}, fmt.Errorf("缺少 oidc 配置")
+		}
+		o := a.OIDC
+		if len(o.AllowedEmails)+len(o.AllowedDomains)+len(o.AllowedGroups) == 0 {
+			return authproxy.Config{}, fmt.Errorf("oidc 至少需要配置一个允许列表（邮箱/域名/用户组）")
+		}
+		pc.OIDC = &authproxy.OIDCConfig{
+			Issuer:         o.Issuer,
+			ClientID:       o.ClientID,
+			ClientSecret:   o.ClientSecret,
+			RedirectURL:    o.RedirectURL,
+			Scopes:         o.Scopes,
+			GroupsClaim:    o.GroupsClaim,
+			AllowedEmails:  o.AllowedEmails,
+			AllowedDomains: o.AllowedDomains,
+			AllowedGroups:  o.AllowedGroups,
+		}
+	case authproxy.ModeAccess:
+		if a.Access == nil || a.Access.TeamDomain == "" || a.Access.Audience == "" {
+			return authproxy.Config{}, fmt.Errorf("access 需要配置 team_domain 和 audience")
+		}
+		pc.Access = &authproxy.AccessConfig{TeamDomain: a.Access.TeamDomain, Audience: a.Access.Audience}
+	}
+	return pc, nil
+}
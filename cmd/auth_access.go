@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accessTeam     string
+	accessAudience string
+	accessDisable  bool
+)
+
+func init() {
+	authAccessCmd.Flags().StringVar(&accessTeam, "team", "", "Cloudflare Zero Trust 团队域名 (如 myteam.cloudflareaccess.com)")
+	authAccessCmd.Flags().StringVar(&accessAudience, "aud", "", "Access 应用的 AUD 标签")
+	authAccessCmd.Flags().BoolVar(&accessDisable, "disable", false, "关闭 Access 校验，恢复用户名密码登录")
+	authCmd.AddCommand(authAccessCmd)
+}
+
+var authAccessCmd = &cobra.Command{
+	Use:   "access <路由>",
+	Short: "为路由启用 Cloudflare Access JWT 校验（替代登录页）",
+	Long: `适用于已在 Cloudflare Zero Trust 中配置了 Access 应用的域名：登录交给 Access 完成，
+鉴权代理校验每个请求的 Cf-Access-Jwt-Assertion，防止绕过 Access 直接访问源站，
+并将已验证的邮箱通过 Cf-Access-Authenticated-User-Email 头转发给上游。
+
+AUD 标签可在 Zero Trust 控制台的 Access 应用概览页找到。
+
+示例:
+  cftunnel auth access admin --team myteam.cloudflareaccess.com --aud 4714c1358e65fe4b...`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], !accessDisable)
+		if err != nil {
+			return err
+		}
+
+		if accessDisable {
+			if len(route.Auth.Users) == 0 && len(route.Auth.APIKeys) == 0 {
+				return fmt.Errorf("路由 %s 没有用户或 API Key，请先执行 cftunnel auth user add %s <用户名>", route.Name, route.Name)
+			}
+			route.Auth.Mode = ""
+			route.Auth.Access = nil
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			if len(route.Auth.Users) == 0 {
+				fmt.Printf("✔ 路由 %s 已恢复用户名密码模式，当前没有用户，仅接受 API Key\n", route.Name)
+				return nil
+			}
+			fmt.Printf("✔ 路由 %s 已恢复用户名密码登录\n", route.Name)
+			return nil
+		}
+
+		a := route.Auth.Access
+		if a == nil {
+			a = &config.AccessConfig{}
+		}
+		if cmd.Flags().Changed("team") {
+			a.TeamDomain = strings.TrimSpace(accessTeam)
+		}
+		if cmd.Flags().Changed("aud") {
+			a.Audience = strings.TrimSpace(accessAudience)
+		}
+		if a.TeamDomain == "" || a.Audience == "" {
+			return fmt.Errorf("--team 和 --aud 不能为空")
+		}
+
+		route.Auth.Mode = authproxy.ModeAccess
+		route.Auth.Access = a
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 路由 %s 已启用 Cloudflare Access 校验 (%s)\n", route.Name, authproxy.AccessIssuer(a.TeamDomain))
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
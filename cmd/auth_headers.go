@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"net/textproto"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	headersUser   string
+	headersEmail  string
+	headersGroups string
+	headersReset  bool
+)
+
+func init() {
+	authHeadersCmd.Flags().StringVar(&headersUser, "user", "", "用户名头 (默认 X-Forwarded-User，- 表示不发送)")
+	authHeadersCmd.Flags().StringVar(&headersEmail, "email", "", "邮箱头 (默认 X-Forwarded-Email，- 表示不发送)")
+	authHeadersCmd.Flags().StringVar(&headersGroups, "groups", "", "用户组头 (默认 X-Auth-Request-Groups，- 表示不发送)")
+	authHeadersCmd.Flags().BoolVar(&headersReset, "reset", false, "恢复默认身份头")
+	authCmd.AddCommand(authHeadersCmd)
+}
+
+var authHeadersCmd = &cobra.Command{
+	Use:   "headers <路由>",
+	Short: "查看或设置转发给上游的身份头",
+	Long: `登录成功后，鉴权代理会把用户身份写入请求头转发给上游应用，便于应用按用户映射账号。
+客户端自行携带的同名头会被清除，本代理的 Cookie 也不会转发给上游。
+
+示例:
+  cftunnel auth headers grafana                           # 查看当前配置
+  cftunnel auth headers grafana --user X-WEBAUTH-USER     # Grafana auth.proxy
+  cftunnel auth headers grafana --groups -                # 不发送用户组`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		a := route.Auth
+
+		changed := headersReset || cmd.Flags().Changed("user") || cmd.Flags().Changed("email") || cmd.Flags().Changed("groups")
+		if changed {
+			h := a.IdentityHeaders
+			if h == nil || headersReset {
+				h = &config.IdentityHeaders{}
+			}
+			for _, f := range []struct {
+				flag string
+				val  string
+				dst  *string
+			}{
+				{"user", headersUser, &h.User},
+				{"email", headersEmail, &h.Email},
+				{"groups", headersGroups, &h.Groups},
+			} {
+				if !cmd.Flags().Changed(f.flag) {
+					continue
+				}
+				if f.val != "" && f.val != "-" {
+					f.val = textproto.CanonicalMIMEHeaderKey(f.val)
+				}
+				*f.dst = f.val
+			}
+			a.IdentityHeaders = h
+			if *h == (config.IdentityHeaders{}) {
+				a.IdentityHeaders = nil
+			}
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("✔ 路由 %s 的身份头已更新\n", route.Name)
+		}
+
+		var h config.IdentityHeaders
+		if a.IdentityHeaders != nil {
+			h = *a.IdentityHeaders
+		}
+		def := authproxy.DefaultIdentityHeaders
+		fmt.Printf("  用户名: %s\n", describeHeader(identityHeader(h.User, def.User)))
+		fmt.Printf("  邮箱:   %s\n", describeHeader(identityHeader(h.Email, def.Email)))
+		fmt.Printf("  用户组: %s\n", describeHeader(identityHeader(h.Groups, def.Groups)))
+		if changed {
+			fmt.Println("重新执行 cftunnel up 后生效")
+		}
+		return nil
+	},
+}
+
+func describeHeader(name string) string {
+	if name == "" {
+		return "(不发送)"
+	}
+	return name
+}
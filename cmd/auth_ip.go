@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	authIPAllow []string
+	authIPDeny  []string
+	authIPClear bool
+)
+
+func init() {
+	authIPCmd.Flags().StringSliceVar(&authIPAllow, "allow", nil, "只允许这些 IP/CIDR 访问（覆盖原列表）")
+	authIPCmd.Flags().StringSliceVar(&authIPDeny, "deny", nil, "拒绝这些 IP/CIDR 访问（覆盖原列表）")
+	authIPCmd.Flags().BoolVar(&authIPClear, "clear", false, "清空该路由的所有 IP 规则")
+	authCmd.AddCommand(authIPCmd)
+}
+
+var authIPCmd = &cobra.Command{
+	Use:   "ip <路由>",
+	Short: "查看或设置路由的 IP 访问规则",
+	Long: `查看或设置路由的 IP 访问规则，客户端地址取自 Cloudflare 的 CF-Connecting-IP。
+拒绝列表优先；配置了允许列表时只放行列表内的地址。未启用密码保护的路由也会启动代理执行 IP 规则。
+
+示例:
+  cftunnel auth ip admin --allow 203.0.113.0/24 --allow 198.51.100.7
+  cftunnel auth ip admin --clear`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		route := cfg.FindRoute(args[0])
+		if route == nil {
+			return fmt.Errorf("路由 %s 不存在", args[0])
+		}
+
+		changed := authIPClear || cmd.Flags().Changed("allow") || cmd.Flags().Changed("deny")
+		if !changed {
+			printIPRules(route)
+			return nil
+		}
+		if authIPClear {
+			route.AllowIPs, route.DenyIPs = nil, nil
+		} else if err := requireHTTPService(route); err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("allow") {
+			if _, err := authproxy.ParsePrefixes(authIPAllow); err != nil {
+				return err
+			}
+			route.AllowIPs = authIPAllow
+		}
+		if cmd.Flags().Changed("deny") {
+			if _, err := authproxy.ParsePrefixes(authIPDeny); err != nil {
+				return err
+			}
+			route.DenyIPs = authIPDeny
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println("✔ IP 规则已更新，重新执行 cftunnel up 后生效")
+		printIPRules(route)
+		return nil
+	},
+}
+
+func printIPRules(r *config.RouteConfig) {
+	if len(r.AllowIPs) == 0 && len(r.DenyIPs) == 0 {
+		fmt.Printf("路由 %s 未配置 IP 规则\n", r.Name)
+		return
+	}
+	if len(r.AllowIPs) > 0 {
+		fmt.Printf("  允许: %s\n", strings.Join(r.AllowIPs, ", "))
+	}
+	if len(r.DenyIPs) > 0 {
+		fmt.Printf("  拒绝: %s\n", strings.Join(r.DenyIPs, ", "))
+	}
+}
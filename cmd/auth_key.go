@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var authKeyHeader string
+
+var authKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "管理机器客户端使用的 API Key",
+	Long: `为 Webhook、CI、命令行等机器客户端创建 API Key，请求时携带以下任一方式即可跳过登录页：
+
+  Authorization: Bearer cft_xxx
+  <自定义头>: cft_xxx          （通过 --header 设置，如 X-API-Key）
+
+也可直接使用 Authorization: Basic 携带路由的用户名和密码（未启用两步验证的用户）。`,
+}
+
+func init() {
+	authKeyCreateCmd.Flags().StringVar(&authKeyHeader, "header", "", "同时接受从该请求头读取 API Key (如 X-API-Key)")
+	authKeyCmd.AddCommand(authKeyCreateCmd, authKeyRevokeCmd, authKeyListCmd)
+	authCmd.AddCommand(authKeyCmd)
+}
+
+var authKeyCreateCmd = &cobra.Command{
+	Use:   "create <路由> <名称>",
+	Short: "创建 API Key（明文只显示一次）",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], true)
+		if err != nil {
+			return err
+		}
+		if route.Auth.FindAPIKey(args[1]) != nil {
+			return fmt.Errorf("API Key %s 已存在，如需重新生成请先 revoke", args[1])
+		}
+		key, hash := authproxy.GenerateAPIKey()
+		route.Auth.APIKeys = append(route.Auth.APIKeys, config.AuthAPIKey{Name: args[1], Hash: hash, Created: time.Now()})
+		if cmd.Flags().Changed("header") {
+			route.Auth.APIKeyHeader = authKeyHeader
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ API Key 已创建: %s\n\n  %s\n\n", args[1], key)
+		fmt.Println("请立即保存，该密钥不会再次显示。重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
+
+var authKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <路由> <名称>",
+	Short: "吊销 API Key",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		if !route.Auth.RemoveAPIKey(args[1]) {
+			return fmt.Errorf("API Key %s 不存在", args[1])
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ API Key 已吊销: %s，重新执行 cftunnel up 后生效\n", args[1])
+		return nil
+	},
+}
+
+var authKeyListCmd = &cobra.Command{
+	Use:   "list <路由>",
+	Short: "列出路由的 API Key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		if len(route.Auth.APIKeys) == 0 {
+			fmt.Println("暂无 API Key")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "名称\t创建时间")
+		fmt.Fprintln(w, "----\t--------")
+		for _, k := range route.Auth.APIKeys {
+			fmt.Fprintf(w, "%s\t%s\n", k.Name, k.Created.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		if route.Auth.APIKeyHeader != "" {
+			fmt.Printf("\n自定义请求头: %s\n", route.Auth.APIKeyHeader)
+		}
+		return nil
+	},
+}
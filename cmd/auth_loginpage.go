@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loginPageTemplate string
+	loginPageTitle    string
+	loginPageLogo     string
+	loginPageReset    bool
+)
+
+func init() {
+	authLoginPageCmd.Flags().StringVar(&loginPageTemplate, "template", "", "自定义登录页模板文件 (Go html/template)")
+	authLoginPageCmd.Flags().StringVar(&loginPageTitle, "title", "", "登录页标题")
+	authLoginPageCmd.Flags().StringVar(&loginPageLogo, "logo", "", "Logo 图片 URL 或本地图片文件")
+	authLoginPageCmd.Flags().BoolVar(&loginPageReset, "reset", false, "恢复内置登录页")
+	authCmd.AddCommand(authLoginPageCmd)
+}
+
+var authLoginPageCmd = &cobra.Command{
+	Use:   "login-page <路由>",
+	Short: "定制路由的登录页（模板、标题、Logo）",
+	Long: `为路由设置自定义标题与 Logo，或使用完全自定义的 HTML 模板（Go html/template 语法）。
+本地 Logo 文件会在 cftunnel up 时内联进页面，无需额外托管。
+
+模板可使用的变量:
+  .Route     路由名称          .Hostname  访问域名
+  .Title     自定义标题        .Logo      Logo 地址
+  .Error     错误提示          .MFA       是否处于动态码输入步骤
+  .CSRF      CSRF 令牌         .State     登录后返回地址
+
+自定义模板必须包含隐藏字段 csrf 与 state，登录表单 POST 到 /___auth/login（字段 username、password），
+动态码表单 POST 到 /___auth/totp（字段 code）。
+
+示例:
+  cftunnel auth login-page demo --title "客户演示环境" --logo ./logo.png
+  cftunnel auth login-page demo --template ./login.html
+  cftunnel auth login-page demo --reset`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		if loginPageReset {
+			route.Auth.LoginPage = nil
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("✔ 路由 %s 已恢复内置登录页\n", route.Name)
+			return nil
+		}
+
+		lp := route.Auth.LoginPage
+		if lp == nil {
+			lp = &config.LoginPage{}
+		}
+		if cmd.Flags().Changed("template") {
+			lp.Template = ""
+			if loginPageTemplate != "" {
+				if lp.Template, err = filepath.Abs(loginPageTemplate); err != nil {
+					return err
+				}
+				if _, err := authproxy.ParseLoginTemplate(lp.Template); err != nil {
+					return err
+				}
+			}
+		}
+		if cmd.Flags().Changed("title") {
+			lp.Title = loginPageTitle
+		}
+		if cmd.Flags().Changed("logo") {
+			lp.Logo = loginPageLogo
+			logo, err := authproxy.LoadLogo(lp.Logo)
+			if err != nil {
+				return err
+			}
+			// 本地文件保存绝对路径，避免 up 时工作目录不同导致找不到
+			if string(logo) != lp.Logo {
+				lp.Logo, _ = filepath.Abs(lp.Logo)
+			}
+		}
+
+		route.Auth.LoginPage = lp
+		if *lp == (config.LoginPage{}) {
+			route.Auth.LoginPage = nil
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 路由 %s 的登录页已更新\n", route.Name)
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	oidcIssuer       string
+	oidcClientID     string
+	oidcClientSecret string
+	oidcRedirectURL  string
+	oidcGroupsClaim  string
+	oidcAllowEmails  []string
+	oidcAllowDomains []string
+	oidcAllowGroups  []string
+	oidcDisable      bool
+)
+
+func init() {
+	authOIDCCmd.Flags().StringVar(&oidcIssuer, "issuer", "", "OIDC Issuer 地址 (如 https://sso.example.com/realms/corp)")
+	authOIDCCmd.Flags().StringVar(&oidcClientID, "client-id", "", "客户端 ID")
+	authOIDCCmd.Flags().StringVar(&oidcClientSecret, "client-secret", "", "客户端密钥（公共客户端可留空，仅使用 PKCE）")
+	authOIDCCmd.Flags().StringVar(&oidcRedirectURL, "redirect-url", "", "回调地址 (默认 https://<域名>/___auth/callback)")
+	authOIDCCmd.Flags().StringVar(&oidcGroupsClaim, "groups-claim", "", "用户组所在的 claim 名称 (默认 groups)")
+	authOIDCCmd.Flags().StringSliceVar(&oidcAllowEmails, "allow-email", nil, "允许登录的邮箱，可重复指定")
+	authOIDCCmd.Flags().StringSliceVar(&oidcAllowDomains, "allow-domain", nil, "允许登录的邮箱域名，可重复指定")
+	authOIDCCmd.Flags().StringSliceVar(&oidcAllowGroups, "allow-group", nil, "允许登录的用户组，可重复指定")
+	authOIDCCmd.Flags().BoolVar(&oidcDisable, "disable", false, "关闭 OIDC，恢复用户名密码登录")
+	authCmd.AddCommand(authOIDCCmd)
+}
+
+var authOIDCCmd = &cobra.Command{
+	Use:   "oidc <路由>",
+	Short: "为路由启用 OpenID Connect 登录（替代用户名密码）",
+	Long: `为路由启用 OpenID Connect 登录，使用授权码 + PKCE 流程对接 Keycloak、Authentik 等身份提供方。
+
+在身份提供方中将回调地址设置为 https://<域名>/___auth/callback。
+
+示例:
+  cftunnel auth oidc admin --issuer https://sso.example.com/realms/corp \
+    --client-id cftunnel --client-secret xxx --allow-domain example.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], !oidcDisable)
+		if err != nil {
+			return err
+		}
+
+		if oidcDisable {
+			if len(route.Auth.Users) == 0 && len(route.Auth.APIKeys) == 0 {
+				return fmt.Errorf("路由 %s 没有用户或 API Key，请先执行 cftunnel auth user add %s <用户名>", route.Name, route.Name)
+			}
+			route.Auth.Mode = ""
+			route.Auth.OIDC = nil
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			if len(route.Auth.Users) == 0 {
+				fmt.Printf("✔ 路由 %s 已恢复用户名密码模式，当前没有用户，仅接受 API Key\n", route.Name)
+				return nil
+			}
+			fmt.Printf("✔ 路由 %s 已恢复用户名密码登录\n", route.Name)
+			return nil
+		}
+
+		o := route.Auth.OIDC
+		if o == nil {
+			o = &config.OIDCConfig{}
+		}
+		if cmd.Flags().Changed("issuer") {
+			o.Issuer = strings.TrimSpace(oidcIssuer)
+		}
+		if cmd.Flags().Changed("client-id") {
+			o.ClientID = strings.TrimSpace(oidcClientID)
+		}
+		if cmd.Flags().Changed("client-secret") {
+			o.ClientSecret = oidcClientSecret
+		}
+		if cmd.Flags().Changed("redirect-url") {
+			o.RedirectURL = oidcRedirectURL
+		}
+		if cmd.Flags().Changed("groups-claim") {
+			o.GroupsClaim = oidcGroupsClaim
+		}
+		if cmd.Flags().Changed("allow-email") {
+			o.AllowedEmails = oidcAllowEmails
+		}
+		if cmd.Flags().Changed("allow-domain") {
+			o.AllowedDomains = oidcAllowDomains
+		}
+		if cmd.Flags().Changed("allow-group") {
+			o.AllowedGroups = oidcAllowGroups
+		}
+
+		if o.Issuer == "" || o.ClientID == "" {
+			return fmt.Errorf("--issuer 和 --client-id 不能为空")
+		}
+		if len(o.AllowedEmails)+len(o.AllowedDomains)+len(o.AllowedGroups) == 0 {
+			return fmt.Errorf("请至少指定一个 --allow-email、--allow-domain 或 --allow-group")
+		}
+
+		route.Auth.Mode = authproxy.ModeOIDC
+		route.Auth.OIDC = o
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 路由 %s 已启用 OIDC 登录 (%s)\n", route.Name, o.Issuer)
+		fmt.Printf("  回调地址: https://%s/___auth/callback\n", route.Hostname)
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	authCmd.AddCommand(authPublicCmd)
+}
+
+var authPublicCmd = &cobra.Command{
+	Use:   "public <路由> [路径...]",
+	Short: "设置无需登录即可访问的路径（不带路径则清空）",
+	Long: `为受保护路由开放部分路径，例如 Webhook 回调、健康检查或静态资源，其余路径仍需登录。
+路径支持 glob，以 /* 结尾匹配该前缀下任意层级。
+
+示例:
+  cftunnel auth public gitea /webhook/* /healthz
+  cftunnel auth public gitea            # 清空，全部路径均需登录`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		paths := args[1:]
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("路径 %s 必须以 / 开头", p)
+			}
+			if _, err := path.Match(p, "/"); err != nil {
+				return fmt.Errorf("路径 %s 格式无效: %w", p, err)
+			}
+		}
+		route.Auth.PublicPaths = paths
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			fmt.Printf("✔ 路由 %s 的所有路径均需登录\n", route.Name)
+		} else {
+			fmt.Printf("✔ 路由 %s 以下路径无需登录: %s\n", route.Name, strings.Join(paths, ", "))
+		}
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var rotateGrace time.Duration
+
+func init() {
+	authRotateKeyCmd.Flags().DurationVar(&rotateGrace, "grace", 0, "旧密钥的宽限期，期间已登录的会话仍然有效 (默认等于 Cookie 有效期，0s 表示立即失效)")
+	authCmd.AddCommand(authRotateKeyCmd)
+}
+
+var authRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key <路由>",
+	Short: "轮换路由的 Cookie 签名密钥",
+	Long: `生成新的签名密钥用于签发 Cookie，旧密钥转为只校验，在宽限期结束后失效。
+宽限期内已登录的用户无需重新登录；怀疑密钥泄露时可使用 --grace 0s 让所有会话立即失效。
+
+示例:
+  cftunnel auth rotate-key admin
+  cftunnel auth rotate-key admin --grace 1h`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		a := route.Auth
+		grace := rotateGrace
+		if !cmd.Flags().Changed("grace") {
+			grace = time.Duration(a.CookieTTLOrDefault()) * time.Second
+		}
+		if grace < 0 {
+			return fmt.Errorf("--grace 不能为负数")
+		}
+
+		// 清理已过期的旧密钥
+		now := time.Now()
+		var retired []config.AuthRetiredKey
+		for _, k := range a.RetiredKeys {
+			if now.Before(k.Expires) {
+				retired = append(retired, k)
+			}
+		}
+		oldID := ""
+		if old, err := hex.DecodeString(a.SigningKey); err == nil && len(old) > 0 {
+			oldID = authproxy.KeyID(old)
+			if grace > 0 {
+				retired = append(retired, config.AuthRetiredKey{Key: a.SigningKey, Expires: now.Add(grace)})
+			}
+		}
+		key := authproxy.RandomKey()
+		a.SigningKey = hex.EncodeToString(key)
+		a.RetiredKeys = retired
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✔ 路由 %s 已启用新的签名密钥 %s\n", route.Name, authproxy.KeyID(key))
+		switch {
+		case oldID == "":
+		case grace > 0:
+			fmt.Printf("  旧密钥 %s 在 %s 前仍可校验已登录的会话\n", oldID, now.Add(grace).Format("2006-01-02 15:04"))
+		default:
+			fmt.Printf("  旧密钥 %s 已立即失效，所有用户需重新登录\n", oldID)
+		}
+		if len(retired) > 0 {
+			fmt.Printf("  当前共有 %d 个旧密钥处于宽限期\n", len(retired))
+		}
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// minSessionPrefix --revoke 接受的最短会话 ID 前缀，避免过短的前缀误注销多个会话
+const minSessionPrefix = 8
+
+var (
+	sessionsRevoke     string
+	sessionsRevokeUser string
+	sessionsRevokeAll  bool
+)
+
+func init() {
+	authSessionsCmd.Flags().StringVar(&sessionsRevoke, "revoke", "", "注销指定 ID（前缀即可）的会话")
+	authSessionsCmd.Flags().StringVar(&sessionsRevokeUser, "revoke-user", "", "注销指定用户的全部会话")
+	authSessionsCmd.Flags().BoolVar(&sessionsRevokeAll, "revoke-all", false, "注销路由的全部会话")
+	authCmd.AddCommand(authSessionsCmd)
+}
+
+var authSessionsCmd = &cobra.Command{
+	Use:   "sessions <路由>",
+	Short: "查看或注销路由的登录会话",
+	Long: `查看或注销路由的登录会话，运行中的鉴权代理会立即生效，无需重启。
+
+修改用户密码或两步验证后，该用户已有的会话会自动失效；轮换签名密钥后，旧会话在宽限期结束时失效。`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		route := cfg.FindRoute(args[0])
+		if route == nil {
+			return fmt.Errorf("路由 %s 不存在", args[0])
+		}
+		path := config.SessionPath(route.Name)
+		if sessionsRevoke != "" && len(sessionsRevoke) < minSessionPrefix {
+			return fmt.Errorf("--revoke 的会话 ID 前缀至少需要 %d 个字符", minSessionPrefix)
+		}
+
+		if sessionsRevoke != "" || sessionsRevokeUser != "" || sessionsRevokeAll {
+			n, err := authproxy.RevokeSessions(path, func(s authproxy.Session) bool {
+				switch {
+				case sessionsRevokeAll:
+					return true
+				case sessionsRevokeUser != "":
+					return s.Username == sessionsRevokeUser
+				default:
+					return strings.HasPrefix(s.ID, sessionsRevoke)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✔ 已注销 %d 个会话\n", n)
+			return nil
+		}
+
+		list, err := authproxy.ListSessions(path)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("暂无有效会话")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t用户\t客户端 IP\t登录时间\t过期时间")
+		fmt.Fprintln(w, "--\t----\t---------\t--------\t--------")
+		for _, s := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.ID[:12], s.Username, s.ClientIP,
+				s.Created.Local().Format("2006-01-02 15:04"), s.Expires.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/spf13/cobra"
+	"rsc.io/qr"
+)
+
+var authTOTPDisable bool
+
+func init() {
+	authUserTOTPCmd.Flags().BoolVar(&authTOTPDisable, "disable", false, "关闭该用户的两步验证")
+	authUserCmd.AddCommand(authUserTOTPCmd)
+}
+
+var authUserTOTPCmd = &cobra.Command{
+	Use:   "totp <路由> <用户名>",
+	Short: "为用户启用两步验证（TOTP 动态码）",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		user := route.Auth.FindUser(args[1])
+		if user == nil {
+			return fmt.Errorf("用户 %s 不存在", args[1])
+		}
+
+		if authTOTPDisable {
+			user.TOTPSecret = ""
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("✔ 已关闭用户 %s 的两步验证\n", user.Username)
+			return nil
+		}
+
+		secret := authproxy.GenerateTOTPSecret()
+		uri := authproxy.TOTPURI("cftunnel", user.Username+"@"+route.Hostname, secret)
+		fmt.Println("请使用认证器 App（Google Authenticator、1Password 等）扫描二维码:")
+		fmt.Println()
+		if err := printQR(uri); err != nil {
+			fmt.Printf("警告: 生成二维码失败: %v\n", err)
+		}
+		fmt.Printf("无法扫码时可手动添加:\n  %s\n  密钥: %s\n\n", uri, secret)
+
+		// 先确认动态码可用再保存，避免配置错误导致无法登录
+		var code string
+		err = huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().Title("输入 App 中显示的 6 位动态码以确认").Value(&code),
+			),
+		).Run()
+		if err != nil {
+			return err
+		}
+		if _, ok := authproxy.ValidateTOTP(secret, code, time.Now()); !ok {
+			return fmt.Errorf("动态码校验失败，未启用两步验证，请重试")
+		}
+
+		user.TOTPSecret = secret
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 已为用户 %s 启用两步验证，重新执行 cftunnel up 后生效\n", user.Username)
+		return nil
+	},
+}
+
+// printQR 在终端中以字符块输出二维码（适配深色背景）
+func printQR(text string) error {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return err
+	}
+	const quiet = 2
+	var b strings.Builder
+	for y := -quiet; y < code.Size+quiet; y++ {
+		for x := -quiet; x < code.Size+quiet; x++ {
+			if code.Black(x, y) {
+				b.WriteString("  ")
+			} else {
+				b.WriteString("██")
+			}
+		}
+		b.WriteString("\n")
+	}
+	fmt.Print(b.String())
+	return nil
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/huh"
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var authUserPassword string
+
+var authUserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "管理路由的访问用户",
+}
+
+func init() {
+	authUserAddCmd.Flags().StringVar(&authUserPassword, "password", "", "用户密码（留空则交互输入）")
+	authUserCmd.AddCommand(authUserAddCmd, authUserRemoveCmd, authUserListCmd, authUserImportCmd)
+	authCmd.AddCommand(authUserCmd)
+}
+
+// loadRouteAuth 加载配置并返回指定路由，create 为 true 时为未启用鉴权的路由初始化鉴权配置
+func loadRouteAuth(name string, create bool) (*config.Config, *config.RouteConfig, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	route := cfg.FindRoute(name)
+	if route == nil {
+		return nil, nil, fmt.Errorf("路由 %s 不存在", name)
+	}
+	if route.Auth == nil {
+		if !create {
+			return nil, nil, fmt.Errorf("路由 %s 未启用密码保护", name)
+		}
+		if err := requireHTTPService(route); err != nil {
+			return nil, nil, err
+		}
+		route.Auth, _ = newRouteAuth(nil)
+	}
+	if err := migrateLegacyAuth(route.Auth); err != nil {
+		return nil, nil, err
+	}
+	return cfg, route, nil
+}
+
+var authUserAddCmd = &cobra.Command{
+	Use:   "add <路由> <用户名>",
+	Short: "添加用户（已存在则更新密码）",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], true)
+		if err != nil {
+			return err
+		}
+		password := authUserPassword
+		if password == "" {
+			err := huh.NewForm(
+				huh.NewGroup(
+					huh.NewInput().Title("密码").Value(&password).EchoMode(huh.EchoModePassword),
+				),
+			).Run()
+			if err != nil {
+				return err
+			}
+		}
+		u, err := newAuthUser(args[1] + ":" + password)
+		if err != nil {
+			return err
+		}
+
+		if existing := route.Auth.FindUser(u.Username); existing != nil {
+			existing.PasswordHash = u.PasswordHash
+			fmt.Printf("✔ 已更新用户 %s 的密码\n", u.Username)
+		} else {
+			route.Auth.Users = append(route.Auth.Users, u)
+			fmt.Printf("✔ 已添加用户 %s 到路由 %s\n", u.Username, route.Name)
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
+
+var authUserRemoveCmd = &cobra.Command{
+	Use:   "remove <路由> <用户名>",
+	Short: "删除用户",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		if route.Auth.FindUser(args[1]) == nil {
+			return fmt.Errorf("用户 %s 不存在", args[1])
+		}
+		// 用户名密码模式下路由至少需要一个用户或 API Key，OIDC / Access 模式不使用本地用户
+		passwordMode := route.Auth.Mode == "" || route.Auth.Mode == authproxy.ModePassword
+		if passwordMode && len(route.Auth.Users) == 1 && len(route.Auth.APIKeys) == 0 {
+			return fmt.Errorf("路由 %s 至少需要保留一个用户或 API Key", route.Name)
+		}
+		route.Auth.RemoveUser(args[1])
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 用户已删除: %s\n", args[1])
+		return nil
+	},
+}
+
+var authUserListCmd = &cobra.Command{
+	Use:   "list <路由>",
+	Short: "列出路由的所有用户",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "用户名\t哈希算法\t两步验证")
+		fmt.Fprintln(w, "------\t--------\t--------")
+		for _, u := range route.Auth.Users {
+			totp := "-"
+			if u.TOTPSecret != "" {
+				totp = "已启用"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", u.Username, hashAlgorithm(u.PasswordHash), totp)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var authUserImportCmd = &cobra.Command{
+	Use:   "import <路由> <htpasswd 文件>",
+	Short: "从 htpasswd 文件导入用户（同名用户覆盖密码）",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], true)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		users, err := authproxy.ParseHtpasswd(f)
+		if err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", args[1], err)
+		}
+		if len(users) == 0 {
+			return fmt.Errorf("%s 中没有用户", args[1])
+		}
+
+		for _, u := range users {
+			if existing := route.Auth.FindUser(u.Username); existing != nil {
+				existing.PasswordHash = u.PasswordHash
+				continue
+			}
+			route.Auth.Users = append(route.Auth.Users, config.AuthUser{Username: u.Username, PasswordHash: u.PasswordHash})
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("✔ 已导入 %d 个用户到路由 %s\n", len(users), route.Name)
+		return nil
+	},
+}
+
+// hashAlgorithm 返回密码哈希的算法名称
+func hashAlgorithm(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return "bcrypt"
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return "argon2id"
+	case strings.HasPrefix(hash, "{SHA}"):
+		return "sha1"
+	}
+	return "未知"
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	authCmd.AddCommand(authWSPublicCmd)
+}
+
+var authWSPublicCmd = &cobra.Command{
+	Use:   "ws-public <路由> [路径...]",
+	Short: "设置无需登录即可连接的 WebSocket 路径（不带路径则清空）",
+	Long: `默认情况下，受保护路由的 WebSocket 连接必须携带有效登录 Cookie，且 Origin 必须与路由域名一致。
+确实需要匿名 WebSocket 的应用可以为特定路径关闭该检查，路径支持 glob，以 /* 结尾匹配任意层级。
+
+示例:
+  cftunnel auth ws-public grafana /api/live/*
+  cftunnel auth ws-public grafana            # 清空，恢复全部校验`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, route, err := loadRouteAuth(args[0], false)
+		if err != nil {
+			return err
+		}
+		paths := args[1:]
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("路径 %s 必须以 / 开头", p)
+			}
+			if _, err := path.Match(p, "/"); err != nil {
+				return fmt.Errorf("路径 %s 格式无效: %w", p, err)
+			}
+		}
+		route.Auth.PublicWebSocketPaths = paths
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			fmt.Printf("✔ 路由 %s 的所有 WebSocket 连接均需登录\n", route.Name)
+		} else {
+			fmt.Printf("✔ 路由 %s 以下路径的 WebSocket 无需登录: %s\n", route.Name, strings.Join(paths, ", "))
+		}
+		fmt.Println("重新执行 cftunnel up 后生效")
+		return nil
+	},
+}
@@ -43,39 +43,70 @@ var destroyCmd = &cobra.Command{
 			}
 		}
 
-		// 停止运行中的进程
-		if daemon.Running() {
+		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
+		ctx := context.Background()
+		t, err := beginTxn(client, ctx, "destroy", cfg.Tunnel.Name, cfg)
+		if err != nil {
+			return err
+		}
+
+		// 停止运行中的进程，操作回滚时重新启动
+		wasRunning := daemon.Running()
+		if wasRunning {
 			fmt.Println("正在停止隧道...")
 			daemon.Stop()
 		}
 
-		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
-		ctx := context.Background()
-
-		// 删除所有 DNS 记录
+		// 删除所有 DNS 记录并恢复被覆盖的原记录（同一域名的多条路由共用一条记录）
+		target := cfg.Tunnel.ID + ".cfargotunnel.com"
+		var steps []config.JournalStep
+		deleted := make(map[string]bool)
 		for _, r := range cfg.Routes {
-			if r.DNSRecordID != "" && r.ZoneID != "" {
-				fmt.Printf("删除 DNS: %s\n", r.Hostname)
-				if err := client.DeleteDNSRecord(ctx, r.ZoneID, r.DNSRecordID); err != nil {
-					fmt.Printf("  警告: %v\n", err)
+			if r.DNSRecordID != "" && r.ZoneID != "" && !deleted[r.DNSRecordID] {
+				deleted[r.DNSRecordID] = true
+				steps = append(steps, deleteCNAMEStep(r.ZoneID, r.Hostname, r.DNSRecordID, target))
+				for _, o := range cfg.Routes {
+					if o.DNSRecordID == r.DNSRecordID {
+						steps = append(steps, restoreSteps(r.ZoneID, r.Hostname, o.PrevDNS)...)
+					}
 				}
 			}
 		}
 
-		// 删除隧道
-		fmt.Println("删除隧道...")
-		if err := client.DeleteTunnel(ctx, cfg.Tunnel.ID); err != nil {
-			fmt.Printf("警告: %v\n", err)
-		}
-
-		// 清空配置
+		// 删除隧道并清空配置
+		steps = append(steps, config.JournalStep{Action: config.StepDeleteTunnel, TunnelID: cfg.Tunnel.ID}, saveConfigStep)
+		tunnel, routes := cfg.Tunnel, cfg.Routes
 		cfg.Tunnel = config.TunnelConfig{}
 		cfg.Routes = nil
-		if err := cfg.Save(); err != nil {
+		if err := t.commit(cfg, steps...); err != nil {
+			if wasRunning {
+				restartAfterRollback(tunnel.Token, routes)
+			}
 			return err
 		}
+		for _, r := range routes {
+			os.Remove(config.SessionPath(r.Name))
+		}
 
 		fmt.Println("隧道已删除，配置已清空")
 		return nil
 	},
 }
+
+// restartAfterRollback 删除隧道失败（如仍有活动连接）且已回滚时，恢复之前运行中的隧道；
+// 回滚未完成时隧道可能已删除，交给 cftunnel recover 处理
+func restartAfterRollback(token string, routes []config.RouteConfig) {
+	if pending, err := config.LoadJournal(); err != nil || pending != nil {
+		return
+	}
+	for _, r := range routes {
+		if needsProxy(r) {
+			fmt.Println("提示: 隧道已停止，受保护路由需运行 cftunnel up 重新启动")
+			return
+		}
+	}
+	fmt.Println("正在重新启动隧道...")
+	if err := daemon.Start(token); err != nil {
+		fmt.Printf("警告: 重新启动隧道失败: %v（请运行 cftunnel up）\n", err)
+	}
+}
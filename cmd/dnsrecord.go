@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+)
+
+// planClaim 规划为域名创建指向隧道的 CNAME 记录。域名已有记录时先列出：
+// 已指向本隧道的 CNAME 需 adopt 接管（直接返回其记录 ID）；其他 A/AAAA/CNAME 需 overwrite，
+// 原记录作为备份返回，删除路由时恢复
+func planClaim(client *cfapi.Client, ctx context.Context, zoneID, hostname, target string, adopt, overwrite bool) (string, []config.DNSBackup, []config.JournalStep, error) {
+	records, err := client.FindDNSRecords(ctx, zoneID, hostname)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(records) == 0 {
+		return "", nil, []config.JournalStep{createCNAMEStep(zoneID, hostname, target)}, nil
+	}
+
+	fmt.Printf("域名 %s 已有 DNS 记录:\n", hostname)
+	for _, rec := range records {
+		fmt.Printf("  %s %s%s\n", rec.Type, rec.Content, proxiedSuffix(rec.Proxied))
+	}
+
+	if len(records) == 1 && records[0].Type == "CNAME" && strings.EqualFold(records[0].Content, target) {
+		if !adopt && !overwrite {
+			return "", nil, nil, fmt.Errorf("该记录已指向本隧道，使用 --adopt 接管")
+		}
+		fmt.Println("接管已有的隧道记录")
+		return records[0].ID, nil, nil, nil
+	}
+	if !overwrite {
+		return "", nil, nil, fmt.Errorf("域名 %s 已被占用，使用 --overwrite 替换（原记录会备份，remove 时恢复）", hostname)
+	}
+
+	var backup []config.DNSBackup
+	var steps []config.JournalStep
+	for _, rec := range records {
+		if rec.Type != "A" && rec.Type != "AAAA" && rec.Type != "CNAME" {
+			return "", nil, nil, fmt.Errorf("无法覆盖 %s 记录，请在 Cloudflare 控制台手动处理", rec.Type)
+		}
+		b := config.DNSBackup{Type: rec.Type, Content: rec.Content, Proxied: rec.Proxied, TTL: rec.TTL}
+		backup = append(backup, b)
+		steps = append(steps, config.JournalStep{Action: config.StepDeleteRecord, ZoneID: zoneID, Hostname: hostname, RecordID: rec.ID, Record: &b})
+	}
+	steps = append(steps, createCNAMEStep(zoneID, hostname, target))
+	return "", backup, steps, nil
+}
+
+// planRelease 规划路由 r 不再使用其域名时的 DNS 变更：其他路由仍使用该域名则把备份交给它并保留记录，
+// 否则删除隧道记录并恢复被覆盖的原记录
+func planRelease(cfg *config.Config, r config.RouteConfig, target string) []config.JournalStep {
+	for i := range cfg.Routes {
+		if other := &cfg.Routes[i]; other.Name != r.Name && strings.EqualFold(other.Hostname, r.Hostname) {
+			fmt.Printf("域名 %s 仍被路由 %s 使用，保留 DNS 记录\n", r.Hostname, other.Name)
+			other.PrevDNS = append(other.PrevDNS, r.PrevDNS...)
+			return nil
+		}
+	}
+	if r.DNSRecordID == "" || r.ZoneID == "" {
+		return nil
+	}
+	return append([]config.JournalStep{deleteCNAMEStep(r.ZoneID, r.Hostname, r.DNSRecordID, target)}, restoreSteps(r.ZoneID, r.Hostname, r.PrevDNS)...)
+}
+
+// deleteCNAMEStep 删除指向隧道的 CNAME，撤销时按原样重建
+func deleteCNAMEStep(zoneID, hostname, recordID, target string) config.JournalStep {
+	s := createCNAMEStep(zoneID, hostname, target)
+	s.Action, s.RecordID = config.StepDeleteRecord, recordID
+	return s
+}
+
+// restoreSteps 重新创建被覆盖的原记录
+func restoreSteps(zoneID, hostname string, backup []config.DNSBackup) []config.JournalStep {
+	var steps []config.JournalStep
+	for _, b := range backup {
+		steps = append(steps, config.JournalStep{Action: config.StepCreateRecord, ZoneID: zoneID, Hostname: hostname, Record: &b})
+	}
+	return steps
+}
+
+func proxiedSuffix(proxied bool) string {
+	if proxied {
+		return " (已代理)"
+	}
+	return ""
+}
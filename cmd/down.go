@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/qingchencloud/cftunnel/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
+var downAll bool
+
 func init() {
+	downCmd.Flags().BoolVar(&downAll, "all", false, "停止所有 profile 的隧道")
 	rootCmd.AddCommand(downCmd)
 }
 
@@ -13,6 +18,18 @@ var downCmd = &cobra.Command{
 	Use:   "down",
 	Short: "停止隧道",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return daemon.Stop()
+		if !downAll {
+			return daemon.Stop()
+		}
+		return forEachProfile(func(name string) error {
+			if !daemon.Running() {
+				return nil
+			}
+			fmt.Printf("[%s] ", name)
+			if err := daemon.Stop(); err != nil {
+				fmt.Printf("警告: %v\n", err)
+			}
+			return nil
+		})
 	},
 }
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	editPort    string
+	editService string
+	editDomain  string
+	editPath    string
+	editAuth    []string
+	editNoAuth  bool
+	editOrigin  originFlags
+
+	editOverwrite, editAdopt bool
+)
+
+func init() {
+	editCmd.Flags().StringVar(&editPort, "port", "", "新的本地端口 (HTTP)")
+	editCmd.Flags().StringVar(&editService, "service", "", "新的服务地址 (格式同 add --service)")
+	editCmd.Flags().StringVar(&editDomain, "domain", "", "新的域名（会迁移 DNS 记录）")
+	editCmd.Flags().StringVar(&editPath, "path", "", "新的路径正则，空字符串表示匹配全部路径")
+	editCmd.Flags().StringArrayVar(&editAuth, "auth", nil, "重设密码保护用户 (格式: 用户名:密码，可重复指定)")
+	editCmd.Flags().BoolVar(&editNoAuth, "no-auth", false, "关闭密码保护")
+	editCmd.Flags().BoolVar(&editOverwrite, "overwrite", false, "新域名已有 A/AAAA/CNAME 记录时替换，原记录备份到配置中，remove 时恢复")
+	editCmd.Flags().BoolVar(&editAdopt, "adopt", false, "新域名已有指向本隧道的 CNAME 时直接接管")
+	editOrigin.register(editCmd)
+	rootCmd.AddCommand(editCmd)
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit <名称>",
+	Short: "修改已有路由（端口、域名、密码保护等）",
+	Long: `原地修改路由配置并同步 ingress，无需删除重建。
+只有域名变化时才会迁移 DNS 记录：先为新域名创建 CNAME，再删除不再使用的旧记录。
+
+示例:
+  cftunnel edit web --port 3001
+  cftunnel edit web --domain app.example.com
+  cftunnel edit web --domain www.example.com --overwrite
+  cftunnel edit web --auth admin:secret
+  cftunnel edit web --no-auth`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		flags := cmd.Flags()
+		if !editFlagsChanged(cmd) {
+			return fmt.Errorf("请至少指定一项修改，可用参数见 cftunnel edit --help")
+		}
+		if editOverwrite && editAdopt {
+			return fmt.Errorf("--overwrite 与 --adopt 只能指定一个")
+		}
+		if (editOverwrite || editAdopt) && !flags.Changed("domain") {
+			return fmt.Errorf("--overwrite 与 --adopt 需与 --domain 一起使用")
+		}
+		if flags.Changed("port") && flags.Changed("service") {
+			return fmt.Errorf("--port 与 --service 只能指定一个")
+		}
+		if flags.Changed("auth") && editNoAuth {
+			return fmt.Errorf("--auth 与 --no-auth 只能指定一个")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if cfg.Tunnel.ID == "" {
+			return fmt.Errorf("请先运行 cftunnel init && cftunnel create <名称>")
+		}
+		route := cfg.FindRoute(name)
+		if route == nil {
+			return fmt.Errorf("路由 %s 不存在", name)
+		}
+		updated := *route
+
+		// 服务地址
+		if flags.Changed("port") || flags.Changed("service") {
+			spec := editService
+			if flags.Changed("port") {
+				spec = editPort
+			}
+			if updated.Service, err = parseService(spec); err != nil {
+				return err
+			}
+		}
+		if flags.Changed("domain") {
+			updated.Hostname = strings.TrimSpace(editDomain)
+			if updated.Hostname == "" {
+				return fmt.Errorf("--domain 不能为空")
+			}
+		}
+		if flags.Changed("path") {
+			if editPath != "" {
+				if _, err := regexp.Compile(editPath); err != nil {
+					return fmt.Errorf("--path 不是有效的正则表达式: %w", err)
+				}
+			}
+			updated.Path = editPath
+		}
+		if updated.Origin != nil {
+			o := *updated.Origin
+			updated.Origin = &o
+		}
+		if updated.Origin, err = editOrigin.apply(cmd, updated.Origin); err != nil {
+			return err
+		}
+
+		// 密码保护：--auth 只替换用户列表，保留签名密钥与其他鉴权设置
+		if editNoAuth {
+			updated.Auth = nil
+		}
+		if flags.Changed("auth") {
+			a, err := newRouteAuth(editAuth)
+			if err != nil {
+				return err
+			}
+			if updated.Auth != nil {
+				users := a.Users
+				a = new(config.AuthProxy)
+				*a = *updated.Auth
+				a.Users, a.Username, a.Password = users, "", ""
+			}
+			updated.Auth = a
+		}
+		if hasAccessRules(updated) {
+			if err := requireHTTPService(&updated); err != nil {
+				return err
+			}
+		}
+
+		for _, r := range cfg.Routes {
+			if r.Name != name && strings.EqualFold(r.Hostname, updated.Hostname) && r.Path == updated.Path {
+				return fmt.Errorf("域名 %s 的路径 %q 已被路由 %s 使用", updated.Hostname, updated.Path, r.Name)
+			}
+		}
+
+		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
+		ctx := context.Background()
+		t, err := beginTxn(client, ctx, "edit", name, cfg)
+		if err != nil {
+			return err
+		}
+
+		// 仅在域名变化时迁移 DNS 记录
+		var steps []config.JournalStep
+		if !strings.EqualFold(updated.Hostname, route.Hostname) {
+			if steps, err = planMoveDNS(client, ctx, cfg, route, &updated); err != nil {
+				return err
+			}
+		}
+
+		authRemoved := route.Auth != nil && updated.Auth == nil
+		*route = updated
+		if err := t.commit(cfg, append(steps, saveConfigStep, pushIngressStep)...); err != nil {
+			return err
+		}
+		if authRemoved {
+			os.Remove(config.SessionPath(name))
+		}
+		fmt.Printf("✔ 路由已更新: %s%s → %s (%s)\n", route.Hostname, pathSuffix(route.Path), route.Service, name)
+		return nil
+	},
+}
+
+// editFlagsChanged 是否指定了任一修改项（不计入 --profile 等全局参数）
+func editFlagsChanged(cmd *cobra.Command) bool {
+	flags := cmd.Flags()
+	for _, name := range []string{"port", "service", "domain", "path", "auth", "no-auth", "overwrite", "adopt"} {
+		if flags.Changed(name) {
+			return true
+		}
+	}
+	return editOrigin.changed(cmd)
+}
+
+// planMoveDNS 规划为路由的新域名准备 DNS 记录，并释放旧域名的记录；
+// 新域名已有记录时按 --adopt / --overwrite 处理
+func planMoveDNS(client *cfapi.Client, ctx context.Context, cfg *config.Config, old, updated *config.RouteConfig) ([]config.JournalStep, error) {
+	target := cfg.Tunnel.ID + ".cfargotunnel.com"
+	var steps []config.JournalStep
+	var shared *config.RouteConfig
+	for i := range cfg.Routes {
+		if r := &cfg.Routes[i]; r.Name != old.Name && strings.EqualFold(r.Hostname, updated.Hostname) {
+			shared = r
+			break
+		}
+	}
+	updated.PrevDNS = nil
+	if shared != nil {
+		updated.ZoneID, updated.DNSRecordID = shared.ZoneID, shared.DNSRecordID
+		fmt.Printf("域名 %s 已由路由 %s 创建 DNS 记录，直接复用\n", updated.Hostname, shared.Name)
+	} else {
+		zone, err := findZoneForDomain(client, ctx, updated.Hostname)
+		if err != nil {
+			return nil, err
+		}
+		recordID, backup, claim, err := planClaim(client, ctx, zone.ID, updated.Hostname, target, editAdopt, editOverwrite)
+		if err != nil {
+			return nil, err
+		}
+		updated.ZoneID, updated.DNSRecordID, updated.PrevDNS = zone.ID, recordID, backup
+		steps = append(steps, claim...)
+	}
+
+	return append(steps, planRelease(cfg, *old, target)...), nil
+}
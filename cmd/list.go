@@ -25,7 +25,7 @@ var listCmd = &cobra.Command{
 		}
 		fmt.Printf("%-12s %-30s %s\n", "名称", "域名", "服务")
 		for _, r := range cfg.Routes {
-			fmt.Printf("%-12s %-30s %s\n", r.Name, r.Hostname, r.Service)
+			fmt.Printf("%-12s %-30s %s\n", r.Name, r.Hostname+pathSuffix(r.Path), r.Service)
 		}
 		return nil
 	},
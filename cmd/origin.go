@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// originFlags 源站连接选项的命令行参数
+type originFlags struct {
+	noTLSVerify      bool
+	caPool           string
+	serverName       string
+	hostHeader       string
+	connectTimeout   time.Duration
+	keepAliveTimeout time.Duration
+	http2Origin      bool
+	disableChunked   bool
+}
+
+func (f *originFlags) register(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.BoolVar(&f.noTLSVerify, "no-tls-verify", false, "不校验源站 HTTPS 证书（自签名证书）")
+	fs.StringVar(&f.caPool, "ca-pool", "", "额外信任的源站 CA 证书文件 (PEM)")
+	fs.StringVar(&f.serverName, "origin-server-name", "", "连接源站时使用的 TLS SNI 主机名")
+	fs.StringVar(&f.hostHeader, "http-host-header", "", "改写发往源站的 Host 头")
+	fs.DurationVar(&f.connectTimeout, "connect-timeout", 0, "连接源站超时 (如 10s)")
+	fs.DurationVar(&f.keepAliveTimeout, "keep-alive-timeout", 0, "源站空闲连接保持时间 (如 90s)")
+	fs.BoolVar(&f.http2Origin, "http2-origin", false, "使用 HTTP/2 连接源站")
+	fs.BoolVar(&f.disableChunked, "disable-chunked-encoding", false, "禁用分块传输编码（兼容部分 WSGI 服务）")
+}
+
+// changed 命令行中是否指定了任一源站连接选项
+func (f *originFlags) changed(cmd *cobra.Command) bool {
+	fs := cmd.Flags()
+	for _, name := range []string{"no-tls-verify", "ca-pool", "origin-server-name", "http-host-header",
+		"connect-timeout", "keep-alive-timeout", "http2-origin", "disable-chunked-encoding"} {
+		if fs.Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 将命令行中显式指定的选项写入 origin，未指定的保持原值；全部为零值时返回 nil
+func (f *originFlags) apply(cmd *cobra.Command, o *config.OriginConfig) (*config.OriginConfig, error) {
+	fs := cmd.Flags()
+	if f.connectTimeout < 0 || f.keepAliveTimeout < 0 {
+		return nil, fmt.Errorf("超时时间不能为负数")
+	}
+	if o == nil {
+		o = &config.OriginConfig{}
+	}
+	if fs.Changed("no-tls-verify") {
+		o.NoTLSVerify = f.noTLSVerify
+	}
+	if fs.Changed("ca-pool") {
+		o.CAPool = f.caPool
+	}
+	if fs.Changed("origin-server-name") {
+		o.OriginServerName = f.serverName
+	}
+	if fs.Changed("http-host-header") {
+		o.HTTPHostHeader = f.hostHeader
+	}
+	if fs.Changed("connect-timeout") {
+		o.ConnectTimeout = int(f.connectTimeout.Seconds())
+	}
+	if fs.Changed("keep-alive-timeout") {
+		o.KeepAliveTimeout = int(f.keepAliveTimeout.Seconds())
+	}
+	if fs.Changed("http2-origin") {
+		o.HTTP2Origin = f.http2Origin
+	}
+	if fs.Changed("disable-chunked-encoding") {
+		o.DisableChunkedEncoding = f.disableChunked
+	}
+	if *o == (config.OriginConfig{}) {
+		return nil, nil
+	}
+	return o, nil
+}
+
+// ingressOrigin 返回下发给 cloudflared 的 originRequest
+// 受保护路由的 cloudflared 只连接本地鉴权代理，TLS、Host 与 HTTP/2 选项由代理连接 service 时使用
+func ingressOrigin(r config.RouteConfig) cfapi.OriginRequest {
+	o := r.Origin
+	if o == nil {
+		return cfapi.OriginRequest{}
+	}
+	req := cfapi.OriginRequest{
+		ConnectTimeout:         int64(o.ConnectTimeout),
+		KeepAliveTimeout:       int64(o.KeepAliveTimeout),
+		DisableChunkedEncoding: o.DisableChunkedEncoding,
+	}
+	if !needsProxy(r) {
+		req.NoTLSVerify = o.NoTLSVerify
+		req.CAPool = o.CAPool
+		req.OriginServerName = o.OriginServerName
+		req.HTTPHostHeader = o.HTTPHostHeader
+		req.HTTP2Origin = o.HTTP2Origin
+	}
+	return req
+}
+
+// routeOrigin 将远端 ingress 的源站选项转换为路由配置（用于导入已有隧道）
+func routeOrigin(req cfapi.OriginRequest) *config.OriginConfig {
+	if req == (cfapi.OriginRequest{}) {
+		return nil
+	}
+	return &config.OriginConfig{
+		NoTLSVerify:            req.NoTLSVerify,
+		CAPool:                 req.CAPool,
+		OriginServerName:       req.OriginServerName,
+		HTTPHostHeader:         req.HTTPHostHeader,
+		ConnectTimeout:         int(req.ConnectTimeout),
+		KeepAliveTimeout:       int(req.KeepAliveTimeout),
+		HTTP2Origin:            req.HTTP2Origin,
+		DisableChunkedEncoding: req.DisableChunkedEncoding,
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/qingchencloud/cftunnel/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "管理多个 profile（每个 profile 有独立的账户、隧道与路由）",
+	Long: `通过全局参数 --profile 选择要操作的 profile，不指定时为 default。
+新 profile 无需预先创建，直接初始化即可:
+
+  cftunnel --profile staging init
+  cftunnel --profile staging create staging-tunnel
+  cftunnel --profile staging up`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "列出所有 profile 及其隧道状态",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current := config.ProfileName()
+		return forEachProfile(func(name string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			mark := " "
+			if name == current {
+				mark = "*"
+			}
+			tunnel := "未创建隧道"
+			if cfg.Tunnel.ID != "" {
+				tunnel = fmt.Sprintf("%s (%s)", cfg.Tunnel.Name, cfg.Tunnel.ID)
+			}
+			state := "已停止"
+			if daemon.Running() {
+				state = fmt.Sprintf("运行中 (PID: %d)", daemon.PID())
+			}
+			fmt.Printf("%s %-12s %s  路由 %d 条  %s\n", mark, name, tunnel, len(cfg.Routes), state)
+			return nil
+		})
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <名称>",
+	Short: "删除 profile 的本地配置（需先 destroy 其隧道）",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == config.DefaultProfile {
+			return fmt.Errorf("default profile 不能删除，如需清空请使用 cftunnel reset")
+		}
+		current := config.ProfileName()
+		defer config.SetProfile(current)
+		if err := config.SetProfile(name); err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if cfg.Tunnel.ID != "" {
+			return fmt.Errorf("profile %s 仍有隧道 %s，请先运行 cftunnel --profile %s destroy", name, cfg.Tunnel.Name, name)
+		}
+		if daemon.Running() {
+			return fmt.Errorf("profile %s 的 cloudflared 仍在运行，请先运行 cftunnel --profile %s down", name, name)
+		}
+		if err := config.RemoveProfile(name); err != nil {
+			return err
+		}
+		os.RemoveAll(filepath.Join(config.Dir(), "sessions", name))
+		fmt.Printf("✔ profile %s 已删除\n", name)
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd, profileRemoveCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+// forEachProfile 依次切换到每个 profile 执行 fn，结束后恢复当前 profile
+func forEachProfile(fn func(name string) error) error {
+	names, err := config.ProfileNames()
+	if err != nil {
+		return err
+	}
+	current := config.ProfileName()
+	defer config.SetProfile(current)
+	for _, name := range names {
+		if err := config.SetProfile(name); err != nil {
+			return err
+		}
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/qingchencloud/cftunnel/internal/authproxy"
+	"github.com/qingchencloud/cftunnel/internal/config"
+)
+
+// ensureProxyPorts 为需要鉴权代理的路由分配固定端口并检查冲突，返回配置是否有改动
+func ensureProxyPorts(cfg *config.Config) (bool, error) {
+	used := make(map[int]string)
+	for _, r := range cfg.Routes {
+		if u, err := url.Parse(r.Service); err == nil && u.Hostname() != "" {
+			if p, err := strconv.Atoi(u.Port()); err == nil {
+				used[p] = r.Name
+			}
+		}
+	}
+
+	// 其他 profile 的代理端口也不能使用，避免多个隧道同时运行时冲突
+	reserved := make(map[int]string)
+	for _, r := range cfg.OtherRoutes() {
+		if r.ProxyPort != 0 {
+			reserved[r.ProxyPort] = r.Name
+		}
+	}
+
+	changed := false
+	for i, r := range cfg.Routes {
+		if r.ProxyPort == 0 || !needsProxy(r) {
+			continue
+		}
+		if other, ok := reserved[r.ProxyPort]; ok {
+			// 手动修改或早期分配导致与其他 profile 重复，重新分配
+			fmt.Printf("路由 %s 的代理端口 %d 已被其他 profile 的路由 %s 使用，重新分配\n", r.Name, r.ProxyPort, other)
+			cfg.Routes[i].ProxyPort = 0
+			changed = true
+			continue
+		}
+		if other, ok := used[r.ProxyPort]; ok {
+			return false, fmt.Errorf("路由 %s 的代理端口 %d 与路由 %s 冲突，请修改配置中的 proxy_port", r.Name, r.ProxyPort, other)
+		}
+		used[r.ProxyPort] = r.Name
+	}
+
+	next := authproxy.DefaultProxyPort
+	for i, r := range cfg.Routes {
+		if r.ProxyPort != 0 || !needsProxy(r) {
+			continue
+		}
+		for ; next < 65536; next++ {
+			_, inUse := used[next]
+			_, isReserved := reserved[next]
+			if !inUse && !isReserved && authproxy.PortAvailable(next) {
+				break
+			}
+		}
+		if next >= 65536 {
+			return changed, fmt.Errorf("没有可分配给路由 %s 的代理端口", r.Name)
+		}
+		cfg.Routes[i].ProxyPort = next
+		used[next] = r.Name
+		changed = true
+	}
+	return changed, nil
+}
+
+// ingressService 返回推送到 ingress 的 service，受保护路由指向其固定的代理端口
+func ingressService(r config.RouteConfig) string {
+	if needsProxy(r) && r.ProxyPort > 0 {
+		return "http://localhost:" + strconv.Itoa(r.ProxyPort)
+	}
+	return r.Service
+}
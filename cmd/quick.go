@@ -10,13 +10,13 @@ import (
 )
 
 var (
-	quickAuth  string
+	quickAuth  []string
 	quickRelay bool
 	quickProto string
 )
 
 func init() {
-	quickCmd.Flags().StringVar(&quickAuth, "auth", "", "启用密码保护 (格式: 用户名:密码)")
+	quickCmd.Flags().StringArrayVar(&quickAuth, "auth", nil, "启用密码保护 (格式: 用户名:密码，可重复指定多个用户)")
 	quickCmd.Flags().BoolVar(&quickRelay, "relay", false, "使用中继模式穿透（需先 relay init）")
 	quickCmd.Flags().StringVar(&quickProto, "proto", "tcp", "中继协议 (tcp/udp)，仅 --relay 时有效")
 	rootCmd.AddCommand(quickCmd)
@@ -31,12 +31,16 @@ var quickCmd = &cobra.Command{
 		if quickRelay {
 			return relay.StartQuick(args[0], quickProto)
 		}
-		if quickAuth != "" {
-			user, pass, err := parseAuth(quickAuth)
+		if len(quickAuth) > 0 {
+			a, err := newRouteAuth(quickAuth)
 			if err != nil {
 				return err
 			}
-			return daemon.StartQuickWithAuth(args[0], user, pass)
+			users, err := proxyUsers(a)
+			if err != nil {
+				return err
+			}
+			return daemon.StartQuickWithAuth(args[0], users)
 		}
 		return daemon.StartQuick(args[0])
 	},
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var recoverFinish bool
+var recoverUndo bool
+
+func init() {
+	recoverCmd.Flags().BoolVar(&recoverFinish, "finish", false, "继续执行剩余步骤，完成中断的操作")
+	recoverCmd.Flags().BoolVar(&recoverUndo, "undo", false, "撤销已执行的步骤，恢复操作前的状态")
+	rootCmd.AddCommand(recoverCmd)
+}
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "完成或撤销中途中断的 add/remove/edit/destroy 操作",
+	Long: `add、remove、edit、destroy 会把每一步远端操作记录到配置目录下的 journal.yml，
+失败时自动回滚。如果进程在操作途中退出（断电、强制结束等），日志会保留下来，
+可通过本命令继续完成或撤销该操作。`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recoverFinish && recoverUndo {
+			return fmt.Errorf("--finish 与 --undo 只能指定一个")
+		}
+		j, err := config.LoadJournal()
+		if err != nil {
+			return fmt.Errorf("读取操作日志失败: %w", err)
+		}
+		if j == nil {
+			fmt.Println("✔ 没有未完成的操作")
+			return nil
+		}
+
+		fmt.Printf("未完成的操作: %s %s（开始于 %s）\n", j.Op, j.Target, j.Started.Local().Format("2006-01-02 15:04:05"))
+		for _, s := range j.Steps {
+			mark := "○"
+			switch s.Status {
+			case config.StepDone:
+				mark = "✔"
+			case config.StepStarted:
+				mark = "?"
+			}
+			fmt.Printf("  %s %s\n", mark, describeStep(s))
+		}
+
+		finish := recoverFinish
+		if !recoverFinish && !recoverUndo {
+			fmt.Print("完成 (f) / 撤销 (u) / 取消 (回车): ")
+			input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			switch strings.TrimSpace(strings.ToLower(input)) {
+			case "f":
+				finish = true
+			case "u":
+			default:
+				fmt.Println("已取消")
+				return nil
+			}
+		}
+
+		// 隧道可能已被删除，凭据以操作前的配置为准
+		auth := j.Before.Auth
+		t := &txn{client: cfapi.New(auth.APIToken, auth.AccountID), ctx: context.Background(), j: j}
+		if finish {
+			if err := t.forward(true); err != nil {
+				return fmt.Errorf("%w（可再次运行 cftunnel recover 重试或撤销）", err)
+			}
+			if err := config.RemoveJournal(); err != nil {
+				return err
+			}
+			fmt.Printf("✔ 操作 %s %s 已完成\n", j.Op, j.Target)
+			return nil
+		}
+		if err := t.rollback(); err != nil {
+			return fmt.Errorf("%w（可再次运行 cftunnel recover 重试）", err)
+		}
+		fmt.Printf("✔ 操作 %s %s 已撤销\n", j.Op, j.Target)
+		return nil
+	},
+}
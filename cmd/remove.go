@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/qingchencloud/cftunnel/internal/cfapi"
 	"github.com/qingchencloud/cftunnel/internal/config"
@@ -31,24 +32,19 @@ var removeCmd = &cobra.Command{
 		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
 		ctx := context.Background()
 
-		// 删除 DNS 记录
-		if route.DNSRecordID != "" && route.ZoneID != "" {
-			fmt.Printf("正在删除 DNS 记录 %s...\n", route.Hostname)
-			if err := client.DeleteDNSRecord(ctx, route.ZoneID, route.DNSRecordID); err != nil {
-				fmt.Printf("警告: 删除 DNS 记录失败: %v\n", err)
-			}
+		t, err := beginTxn(client, ctx, "remove", name, cfg)
+		if err != nil {
+			return err
 		}
 
+		// 删除 DNS 记录并恢复被覆盖的原记录（同一域名还有其他路由时保留），保存配置并推送 ingress
+		removed := *route
 		cfg.RemoveRoute(name)
-		if err := cfg.Save(); err != nil {
+		steps := planRelease(cfg, removed, cfg.Tunnel.ID+".cfargotunnel.com")
+		if err := t.commit(cfg, append(steps, saveConfigStep, pushIngressStep)...); err != nil {
 			return err
 		}
-
-		// 推送 ingress 配置到远端
-		fmt.Println("正在同步 ingress 配置...")
-		if err := pushIngress(client, ctx, cfg); err != nil {
-			fmt.Printf("警告: 推送 ingress 失败: %v\n", err)
-		}
+		os.Remove(config.SessionPath(name))
 
 		fmt.Printf("路由 %s 已删除\n", name)
 		return nil
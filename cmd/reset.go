@@ -14,7 +14,7 @@ import (
 var resetForce bool
 
 func init() {
-	resetCmd.Flags().BoolVar(&resetForce, "force", false, "跳过确认")
+	resetCmd.Flags().BoolVar(&resetForce, "force", false, "跳过确认；删除隧道失败时仍清除本地配置")
 	rootCmd.AddCommand(resetCmd)
 }
 
@@ -22,8 +22,24 @@ var resetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "重置全部（删除隧道 + 清除本地配置）",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		profile := config.ProfileName()
+		if profile == config.DefaultProfile {
+			// 配置文件中还有其他 profile 时不能整体删除，否则会丢失它们隧道的记录
+			names, err := config.ProfileNames()
+			if err != nil {
+				return err
+			}
+			if len(names) > 1 {
+				return fmt.Errorf("还有其他 profile %v，请先逐个运行 cftunnel --profile <名称> reset", names[1:])
+			}
+		}
+
 		if !resetForce {
-			fmt.Println("即将删除隧道并清除所有本地配置，此操作不可恢复！")
+			if profile != config.DefaultProfile {
+				fmt.Printf("即将删除 profile %s 的隧道并清除其本地配置，此操作不可恢复！\n", profile)
+			} else {
+				fmt.Println("即将删除隧道并清除所有本地配置，此操作不可恢复！")
+			}
 			fmt.Print("确认重置？(y/N): ")
 			reader := bufio.NewReader(os.Stdin)
 			input, _ := reader.ReadString('\n')
@@ -38,16 +54,33 @@ var resetCmd = &cobra.Command{
 		if cfg != nil && cfg.Tunnel.ID != "" {
 			destroyForce = true
 			if err := destroyCmd.RunE(cmd, nil); err != nil {
+				// 隧道仍在 Cloudflare 上时清除配置会丢失它的记录，除非用户明确强制
+				if !resetForce {
+					return fmt.Errorf("删除隧道失败，已保留本地配置: %w（处理后重试，或使用 --force 强制清除）", err)
+				}
 				fmt.Printf("警告: 删除隧道失败: %v\n", err)
 			}
 		}
 
-		// 删除配置目录
+		// 命名 profile 只清理自己的配置与会话
 		dir := config.Dir()
+		if profile != config.DefaultProfile {
+			if err := config.RemoveProfile(profile); err != nil {
+				return err
+			}
+			for _, f := range config.ProfileFiles(profile) {
+				os.RemoveAll(f)
+			}
+			fmt.Printf("已清除 profile %s\n", profile)
+			return nil
+		}
+
+		// 删除配置目录
 		if config.Portable() {
 			// 便携模式：只清理数据文件，不删程序自身和 portable 标记
-			for _, name := range []string{"config.yml", "bin", "cloudflared.pid", "cftunnel.log"} {
-				os.RemoveAll(filepath.Join(dir, name))
+			files := append(config.DataFiles(), filepath.Join(dir, "bin"), filepath.Join(dir, "cftunnel.log"))
+			for _, f := range files {
+				os.RemoveAll(f)
 			}
 		} else {
 			if err := os.RemoveAll(dir); err != nil {
@@ -3,11 +3,15 @@
 import (
 	"os"
 
+	"github.com/qingchencloud/cftunnel/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var Version = "dev"
 
+// profileFlag 全局 --profile，选择要操作的账户与隧道
+var profileFlag string
+
 var rootCmd = &cobra.Command{
 	Use:     "cftunnel",
 	Short:   "Cloudflare Tunnel 一键管理工具 (本地内核版)",
@@ -26,12 +30,14 @@ func Execute() {
 func init() {
 	// 我们可以把原来的逻辑移到那些真正需要路径的命令里
 	// 或者通过这种方式判断：如果是 version 命令，就不打印路径
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "使用指定的 profile（独立的账户、隧道与路由），默认为 default")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		// 只有当执行的不是 version 命令时，才打印这些调试信息
 		if cmd.Name() != "version" {
 			checkWindowsVersion()
 			// 如果有需要，可以打印调试信息
 			// fmt.Printf("[绿色版] 运行目录: %s\n", config.Dir())
 		}
+		return config.SetProfile(profileFlag)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// serviceShortcuts 支持 "协议:端口" 简写的协议，如 ssh:22 → ssh://localhost:22
+var serviceShortcuts = []string{"http", "https", "ssh", "rdp", "tcp", "smb"}
+
+// parseService 解析路由的 service，支持纯端口、协议简写与完整 URL，返回 cloudflared 可用的 service
+func parseService(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("service 不能为空")
+	}
+	if port, err := strconv.Atoi(s); err == nil {
+		if port < 1 || port > 65535 {
+			return "", fmt.Errorf("无效的端口: %s", s)
+		}
+		return "http://localhost:" + s, nil
+	}
+
+	scheme, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", fmt.Errorf("无效的 service: %s", s)
+	}
+	switch scheme {
+	case "http_status":
+		code, err := strconv.Atoi(rest)
+		if err != nil || code < 100 || code > 599 {
+			return "", fmt.Errorf("无效的 HTTP 状态码: %s", s)
+		}
+		return s, nil
+	case "unix", "unix+tls":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return "", fmt.Errorf("unix socket 路径不能为空: %s", s)
+		}
+		return scheme + ":" + path, nil
+	}
+
+	for _, sc := range serviceShortcuts {
+		if scheme != sc || strings.HasPrefix(rest, "//") {
+			continue
+		}
+		// 协议简写：ssh:22
+		if port, err := strconv.Atoi(rest); err != nil || port < 1 || port > 65535 {
+			return "", fmt.Errorf("无效的端口: %s", s)
+		}
+		return sc + "://localhost:" + rest, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("无效的 service: %s", s)
+	}
+	for _, sc := range serviceShortcuts {
+		if u.Scheme == sc {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的 service 协议: %s（支持 http、https、ssh、rdp、tcp、smb、unix、unix+tls、http_status）", u.Scheme)
+}
+
+// httpService service 是否为 HTTP 服务，只有 HTTP 服务可以经过鉴权代理
+func httpService(service string) bool {
+	scheme, _, _ := strings.Cut(service, ":")
+	switch scheme {
+	case "http", "https", "unix", "unix+tls":
+		return true
+	}
+	return false
+}
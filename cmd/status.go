@@ -8,7 +8,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var statusAll bool
+
 func init() {
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "查看所有 profile 的隧道状态")
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -16,24 +19,38 @@ var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "查看隧道状态",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load()
-		if err != nil {
-			return err
-		}
-		if cfg.Tunnel.ID == "" {
-			fmt.Println("未初始化，请运行 cftunnel init && cftunnel create <名称>")
-			return nil
-		}
-		fmt.Printf("隧道: %s (%s)\n", cfg.Tunnel.Name, cfg.Tunnel.ID)
-		if daemon.Running() {
-			fmt.Printf("状态: 运行中 (PID: %d)\n", daemon.PID())
-		} else {
-			fmt.Println("状态: 已停止")
-		}
-		fmt.Printf("路由: %d 条\n", len(cfg.Routes))
-		for _, r := range cfg.Routes {
-			fmt.Printf("  %s → %s\n", r.Hostname, r.Service)
+		if !statusAll {
+			return printStatus()
 		}
-		return nil
+		return forEachProfile(func(name string) error {
+			fmt.Printf("== %s ==\n", name)
+			return printStatus()
+		})
 	},
 }
+
+// printStatus 输出当前 profile 的隧道状态
+func printStatus() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Tunnel.ID == "" {
+		fmt.Println("未初始化，请运行 cftunnel init && cftunnel create <名称>")
+		return nil
+	}
+	if name := config.ProfileName(); name != config.DefaultProfile && !statusAll {
+		fmt.Printf("Profile: %s\n", name)
+	}
+	fmt.Printf("隧道: %s (%s)\n", cfg.Tunnel.Name, cfg.Tunnel.ID)
+	if daemon.Running() {
+		fmt.Printf("状态: 运行中 (PID: %d)\n", daemon.PID())
+	} else {
+		fmt.Println("状态: 已停止")
+	}
+	fmt.Printf("路由: %d 条\n", len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		fmt.Printf("  %s%s → %s\n", r.Hostname, pathSuffix(r.Path), r.Service)
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var syncDryRun bool
+var syncYes bool
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "只输出检查结果与修复计划，不做任何修改")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "不逐项确认，直接执行全部修复")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// drift 本地配置与远端的一处不一致及其修复动作
+type drift struct {
+	route  string // 相关路由，多条路由共用域名时以逗号分隔
+	desc   string
+	action string // 重建 / 接管 / 更新 / 覆盖 / 清理
+	// fix 更新配置中的记录 ID 并返回修复所需的远端步骤，为 nil 表示需要手动处理；
+	// ingress 差异的 fix 为 nil，确认的规则最后合并为一次 ingress 推送
+	fix     func() ([]config.JournalStep, error)
+	ingress string // ingress 差异对应规则的 ingressKey，DNS 差异为空
+}
+
+// confirm 在终端询问 y/N
+func confirm(prompt string) bool {
+	fmt.Print(prompt + " (y/N): ")
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(input)) == "y"
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "检查并修复本地配置与 Cloudflare 的差异（DNS + ingress）",
+	Long: `对比 config.yml 与 Cloudflare 远端状态，逐条路由报告差异并提供修复:
+
+  重建  DNS 记录或 ingress 规则在远端丢失
+  接管  远端已有指向本隧道的记录，但配置中的记录 ID 已失效
+  更新  本工具创建的 DNS 记录不再指向本隧道，或 ingress 规则与本地不一致
+  覆盖  域名被其他记录占用，原记录备份到配置中，remove 时恢复
+  清理  远端存在指向本隧道、但本地没有对应路由的 DNS 记录或 ingress 规则
+
+所有修复作为一次操作执行，失败时自动回滚。ingress 差异逐条确认，
+跳过的规则保持远端现状（如保留远端手动添加的规则）。
+
+示例:
+  cftunnel sync --dry-run
+  cftunnel sync -y`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if cfg.Tunnel.ID == "" {
+			return fmt.Errorf("请先运行 cftunnel init && cftunnel create <名称>")
+		}
+		client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
+		ctx := context.Background()
+		var t *txn
+		if !syncDryRun {
+			if t, err = beginTxn(client, ctx, "sync", cfg.Tunnel.Name, cfg); err != nil {
+				return err
+			}
+		}
+
+		dirty, err := ensureProxyPorts(cfg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("正在检查隧道 %s 的 %d 条路由...\n", cfg.Tunnel.Name, len(cfg.Routes))
+		s := &syncer{client: client, ctx: ctx, cfg: cfg, target: cfg.Tunnel.ID + ".cfargotunnel.com"}
+		s.checkDNS()
+		if err := s.checkIngress(); err != nil {
+			return err
+		}
+
+		if len(s.drifts) == 0 {
+			fmt.Println("✔ 本地配置与 Cloudflare 一致")
+			if (dirty || s.dirty) && !syncDryRun {
+				return t.commit(cfg, saveConfigStep)
+			}
+			return nil
+		}
+		for _, d := range s.drifts {
+			action := d.action
+			if d.fix == nil && d.ingress == "" {
+				action = "需手动处理"
+			}
+			fmt.Printf("  [%s] %s → %s\n", d.route, d.desc, action)
+		}
+		if syncDryRun {
+			fmt.Printf("共 %d 处差异（--dry-run，未做任何修改）\n", len(s.drifts))
+			return nil
+		}
+
+		// 逐项确认：DNS 修复收集步骤，配置中的记录 ID 随之更新；ingress 修复记下确认的规则
+		var steps []config.JournalStep
+		var fixed, skipped int
+		save := dirty || s.dirty
+		accepted := make(map[string]bool)
+		for _, d := range s.drifts {
+			if d.fix == nil && d.ingress == "" {
+				skipped++
+				continue
+			}
+			if !syncYes && !confirm(fmt.Sprintf("[%s] %s：%s？", d.route, d.desc, d.action)) {
+				skipped++
+				continue
+			}
+			if d.ingress != "" {
+				accepted[d.ingress] = true
+				fixed++
+				continue
+			}
+			fixSteps, err := d.fix()
+			if err != nil {
+				fmt.Printf("  警告: %v\n", err)
+				skipped++
+				continue
+			}
+			steps = append(steps, fixSteps...)
+			save = true
+			fixed++
+		}
+		if save {
+			steps = append(steps, saveConfigStep)
+		}
+		if len(accepted) > 0 {
+			steps = append(steps, config.JournalStep{Action: config.StepSetIngress, Ingress: s.mergeIngress(accepted), PrevIngress: s.remote})
+		}
+
+		if len(steps) > 0 {
+			if err := t.commit(cfg, steps...); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✔ 已修复 %d 处，跳过 %d 处\n", fixed, skipped)
+		return nil
+	},
+}
+
+// syncer 收集差异：修复只规划步骤，最后作为一次操作执行
+type syncer struct {
+	client *cfapi.Client
+	ctx    context.Context
+	cfg    *config.Config
+	target string
+	drifts []drift
+	remote []cfapi.IngressRule // 远端当前的 ingress 规则
+
+	dirty bool // cfg 在检查时已更新（如 Zone ID），需要保存
+}
+
+// hostRoutes 按域名分组路由（同一域名共用一条 DNS 记录），保持配置中的顺序
+func (s *syncer) hostRoutes() ([]string, map[string][]*config.RouteConfig) {
+	var hosts []string
+	groups := make(map[string][]*config.RouteConfig)
+	for i := range s.cfg.Routes {
+		r := &s.cfg.Routes[i]
+		h := strings.ToLower(r.Hostname)
+		if groups[h] == nil {
+			hosts = append(hosts, h)
+		}
+		groups[h] = append(groups[h], r)
+	}
+	return hosts, groups
+}
+
+func (s *syncer) checkDNS() {
+	hosts, groups := s.hostRoutes()
+	zones := make(map[string]bool)
+	for _, h := range hosts {
+		routes := groups[h]
+		var names []string
+		for _, r := range routes {
+			names = append(names, r.Name)
+		}
+		label := strings.Join(names, ",")
+		if d := s.checkRecord(routes); d != nil {
+			d.route = label
+			s.drifts = append(s.drifts, *d)
+		}
+		if id := routes[0].ZoneID; id != "" {
+			zones[id] = true
+		}
+	}
+
+	// 指向本隧道但已没有路由的记录
+	for zoneID := range zones {
+		records, err := s.client.ListCNAMEsTo(s.ctx, zoneID, s.target)
+		if err != nil {
+			fmt.Printf("警告: %v\n", err)
+			continue
+		}
+		for _, rec := range records {
+			if groups[strings.ToLower(rec.Name)] != nil {
+				continue
+			}
+			s.drifts = append(s.drifts, drift{
+				route:  "-",
+				desc:   fmt.Sprintf("DNS 记录 %s 指向本隧道但没有对应路由", rec.Name),
+				action: "清理",
+				fix: func() ([]config.JournalStep, error) {
+					return []config.JournalStep{deleteCNAMEStep(zoneID, rec.Name, rec.ID, s.target)}, nil
+				},
+			})
+		}
+	}
+}
+
+// checkRecord 检查一个域名的 DNS 记录，一致时返回 nil
+func (s *syncer) checkRecord(routes []*config.RouteConfig) *drift {
+	r := routes[0]
+	// point 将共用该域名的路由指向记录，recordID 为空时由创建记录的步骤回填
+	point := func(zoneID, recordID string) {
+		for _, rt := range routes {
+			rt.ZoneID, rt.DNSRecordID = zoneID, recordID
+		}
+	}
+
+	zoneID := r.ZoneID
+	if zoneID == "" {
+		zone, err := findZoneForDomain(s.client, s.ctx, r.Hostname)
+		if err != nil {
+			return &drift{desc: err.Error()}
+		}
+		zoneID = zone.ID
+	}
+
+	if r.DNSRecordID != "" {
+		rec, err := s.client.GetDNSRecord(s.ctx, zoneID, r.DNSRecordID)
+		if err != nil {
+			return &drift{desc: err.Error()}
+		}
+		if rec != nil {
+			if rec.Type == "CNAME" && strings.EqualFold(rec.Content, s.target) {
+				if zoneID != r.ZoneID {
+					point(zoneID, rec.ID)
+					s.dirty = true
+				}
+				return nil
+			}
+			// 配置中记录的 ID 说明该记录由本工具创建或接管，重新指向本隧道；
+			// 原内容与 --overwrite 一样备份到路由配置，remove 时恢复
+			return &drift{
+				desc:   fmt.Sprintf("DNS 记录 %s 为 %s %s，未指向本隧道", r.Hostname, rec.Type, rec.Content),
+				action: "更新",
+				fix: func() ([]config.JournalStep, error) {
+					b := config.DNSBackup{Type: rec.Type, Content: rec.Content, Proxied: rec.Proxied, TTL: rec.TTL}
+					point(zoneID, "")
+					r.PrevDNS = append(r.PrevDNS, b)
+					return []config.JournalStep{
+						{Action: config.StepDeleteRecord, ZoneID: zoneID, Hostname: r.Hostname, RecordID: rec.ID, Record: &b},
+						createCNAMEStep(zoneID, r.Hostname, s.target),
+					}, nil
+				},
+			}
+		}
+	}
+
+	// 配置中没有记录 ID 或记录已被删除，按域名查找
+	records, err := s.client.FindDNSRecords(s.ctx, zoneID, r.Hostname)
+	if err != nil {
+		return &drift{desc: err.Error()}
+	}
+	for _, rec := range records {
+		if rec.Type == "CNAME" && strings.EqualFold(rec.Content, s.target) {
+			return &drift{
+				desc:   fmt.Sprintf("DNS 记录 %s 存在但配置中的记录 ID 已失效", r.Hostname),
+				action: "接管",
+				fix: func() ([]config.JournalStep, error) {
+					point(zoneID, rec.ID)
+					return nil, nil
+				},
+			}
+		}
+	}
+	if len(records) > 0 {
+		// 不属于本工具的记录按 --overwrite 处理：原记录备份到路由配置，remove 时恢复
+		rec := records[0]
+		return &drift{
+			desc:   fmt.Sprintf("DNS 记录 %s 已被其他记录占用 (%s %s)", r.Hostname, rec.Type, rec.Content),
+			action: "覆盖",
+			fix: func() ([]config.JournalStep, error) {
+				recordID, backup, steps, err := planClaim(s.client, s.ctx, zoneID, r.Hostname, s.target, false, true)
+				if err != nil {
+					return nil, err
+				}
+				point(zoneID, recordID)
+				r.PrevDNS = append(r.PrevDNS, backup...)
+				return steps, nil
+			},
+		}
+	}
+	return &drift{
+		desc:   fmt.Sprintf("DNS 记录 %s 不存在", r.Hostname),
+		action: "重建",
+		fix: func() ([]config.JournalStep, error) {
+			point(zoneID, "")
+			return []config.JournalStep{createCNAMEStep(zoneID, r.Hostname, s.target)}, nil
+		},
+	}
+}
+
+func ingressKey(r cfapi.IngressRule) string {
+	return strings.ToLower(r.Hostname) + "\x00" + r.Path
+}
+
+func (s *syncer) checkIngress() error {
+	remote, err := s.client.GetIngressConfig(s.ctx, s.cfg.Tunnel.ID)
+	if err != nil {
+		return err
+	}
+	s.remote = remote
+
+	remoteRules := make(map[string]cfapi.IngressRule)
+	for _, r := range remote {
+		remoteRules[ingressKey(r)] = r
+	}
+	desired := make(map[string]bool)
+	for _, rule := range ingressRules(s.cfg) {
+		key := ingressKey(rule)
+		desired[key] = true
+		name := "-"
+		if r := s.findRoute(rule); r != nil {
+			name = r.Name
+		}
+		var desc, action string
+		switch cur, ok := remoteRules[key]; {
+		case !ok:
+			desc, action = fmt.Sprintf("远端缺少 ingress 规则 %s%s", rule.Hostname, pathSuffix(rule.Path)), "重建"
+		case cur.Service != rule.Service:
+			desc, action = fmt.Sprintf("ingress 规则 %s%s 指向 %s，与本地不一致", rule.Hostname, pathSuffix(rule.Path), cur.Service), "更新"
+		case cur != rule:
+			desc, action = fmt.Sprintf("ingress 规则 %s%s 的源站选项与本地不一致", rule.Hostname, pathSuffix(rule.Path)), "更新"
+		default:
+			continue
+		}
+		s.drifts = append(s.drifts, drift{route: name, desc: desc, action: action, ingress: key})
+	}
+
+	for _, r := range remote {
+		key := ingressKey(r)
+		if desired[key] {
+			continue
+		}
+		s.drifts = append(s.drifts, drift{
+			route:   "-",
+			desc:    fmt.Sprintf("远端 ingress 规则 %s%s → %s 没有对应路由", r.Hostname, pathSuffix(r.Path), r.Service),
+			action:  "清理",
+			ingress: key,
+		})
+	}
+	return nil
+}
+
+// mergeIngress 生成要推送的 ingress 规则：确认修复的规则按本地配置，其余保持远端现状
+func (s *syncer) mergeIngress(accepted map[string]bool) []cfapi.IngressRule {
+	remote := make(map[string]cfapi.IngressRule)
+	for _, r := range s.remote {
+		remote[ingressKey(r)] = r
+	}
+	var rules []cfapi.IngressRule
+	desired := make(map[string]bool)
+	for _, rule := range ingressRules(s.cfg) {
+		key := ingressKey(rule)
+		desired[key] = true
+		if accepted[key] {
+			rules = append(rules, rule)
+		} else if cur, ok := remote[key]; ok {
+			rules = append(rules, cur)
+		}
+	}
+	// 没有对应路由的远端规则：确认清理的删除，其余保留
+	for _, r := range s.remote {
+		if key := ingressKey(r); !desired[key] && !accepted[key] {
+			rules = append(rules, r)
+		}
+	}
+	orderIngress(rules)
+	return rules
+}
+
+// findRoute 查找生成该 ingress 规则的路由
+func (s *syncer) findRoute(rule cfapi.IngressRule) *config.RouteConfig {
+	for i := range s.cfg.Routes {
+		r := &s.cfg.Routes[i]
+		if strings.EqualFold(r.Hostname, rule.Hostname) && r.Path == rule.Path {
+			return r
+		}
+	}
+	return nil
+}
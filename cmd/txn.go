@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"github.com/qingchencloud/cftunnel/internal/config"
+)
+
+// txn 一次带操作日志的变更：远端副作用逐步执行并落盘，
+// 任何一步失败都会自动回滚，回滚失败时保留日志交给 cftunnel recover
+type txn struct {
+	client *cfapi.Client
+	ctx    context.Context
+	j      *config.Journal
+}
+
+// beginTxn 在修改配置之前调用，记录操作前的配置；上一次操作未收尾时拒绝执行
+func beginTxn(client *cfapi.Client, ctx context.Context, op, target string, before *config.Config) (*txn, error) {
+	pending, err := config.LoadJournal()
+	if err != nil {
+		return nil, fmt.Errorf("读取操作日志失败: %w", err)
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("上一次操作 (%s %s) 未完成，请先运行 cftunnel recover", pending.Op, pending.Target)
+	}
+	snapshot, err := before.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &txn{client: client, ctx: ctx, j: &config.Journal{Op: op, Target: target, Started: time.Now(), Before: snapshot}}, nil
+}
+
+// commit 以 after 为目标配置依次执行 steps
+func (t *txn) commit(after *config.Config, steps ...config.JournalStep) error {
+	t.j.After = after
+	t.j.Steps = steps
+	if err := t.j.Save(); err != nil {
+		return fmt.Errorf("写入操作日志失败: %w", err)
+	}
+	if err := t.forward(false); err != nil {
+		fmt.Printf("操作失败: %v\n正在回滚...\n", err)
+		if rerr := t.rollback(); rerr != nil {
+			return fmt.Errorf("%w；回滚失败: %v（请运行 cftunnel recover 处理）", err, rerr)
+		}
+		return fmt.Errorf("%w（已回滚）", err)
+	}
+	return config.RemoveJournal()
+}
+
+// forward 执行所有未完成的步骤；resume 为 true 时表示从中断处继续，
+// 状态为 started 的步骤可能已经生效，需要先检查远端
+func (t *txn) forward(resume bool) error {
+	for i := range t.j.Steps {
+		s := &t.j.Steps[i]
+		if s.Status == config.StepDone {
+			continue
+		}
+		check := resume && s.Status == config.StepStarted
+		s.Status = config.StepStarted
+		if err := t.j.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("正在%s...\n", describeStep(*s))
+		if err := t.do(s, check); err != nil {
+			return err
+		}
+		s.Status = config.StepDone
+		if err := t.j.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback 逆序撤销已开始的步骤并恢复操作前的配置
+func (t *txn) rollback() error {
+	for i := len(t.j.Steps) - 1; i >= 0; i-- {
+		s := &t.j.Steps[i]
+		if s.Status == "" {
+			continue
+		}
+		if err := t.undo(s); err != nil {
+			t.j.Save()
+			return err
+		}
+		s.Status = ""
+		if err := t.j.Save(); err != nil {
+			return err
+		}
+	}
+	if err := t.j.Before.Save(); err != nil {
+		return err
+	}
+	return config.RemoveJournal()
+}
+
+func (t *txn) do(s *config.JournalStep, check bool) error {
+	switch s.Action {
+	case config.StepCreateRecord:
+		if check {
+			id, err := t.findRecord(s)
+			if err != nil {
+				return err
+			}
+			s.RecordID = id
+		}
+		if s.RecordID == "" {
+			id, err := t.client.CreateDNSRecord(t.ctx, s.ZoneID, stepRecord(s))
+			if err != nil {
+				return err
+			}
+			s.RecordID = id
+		}
+		for i := range t.j.After.Routes {
+			if r := &t.j.After.Routes[i]; strings.EqualFold(r.Hostname, s.Hostname) && r.DNSRecordID == "" {
+				r.ZoneID, r.DNSRecordID = s.ZoneID, s.RecordID
+			}
+		}
+		return nil
+	case config.StepDeleteRecord:
+		rec, err := t.client.GetDNSRecord(t.ctx, s.ZoneID, s.RecordID)
+		if err != nil || rec == nil {
+			return err
+		}
+		return t.client.DeleteDNSRecord(t.ctx, s.ZoneID, s.RecordID)
+	case config.StepDeleteTunnel:
+		if check {
+			exists, err := t.tunnelExists(s.TunnelID)
+			if err != nil || !exists {
+				return err
+			}
+		}
+		return t.client.DeleteTunnel(t.ctx, s.TunnelID)
+	case config.StepSaveConfig:
+		return t.j.After.Save()
+	case config.StepPushIngress:
+		return pushIngress(t.client, t.ctx, t.j.After)
+	case config.StepSetIngress:
+		return t.client.PushIngressConfig(t.ctx, t.j.After.Tunnel.ID, s.Ingress)
+	}
+	return fmt.Errorf("未知的操作步骤 %s", s.Action)
+}
+
+func (t *txn) undo(s *config.JournalStep) error {
+	switch s.Action {
+	case config.StepCreateRecord:
+		if s.RecordID == "" {
+			id, err := t.findRecord(s)
+			if err != nil || id == "" {
+				return err
+			}
+			s.RecordID = id
+		}
+		rec, err := t.client.GetDNSRecord(t.ctx, s.ZoneID, s.RecordID)
+		if err != nil || rec == nil {
+			return err
+		}
+		fmt.Printf("撤销: 删除 DNS 记录 %s %s\n", s.Hostname, rec.Content)
+		return t.client.DeleteDNSRecord(t.ctx, s.ZoneID, s.RecordID)
+	case config.StepDeleteRecord:
+		rec, err := t.client.GetDNSRecord(t.ctx, s.ZoneID, s.RecordID)
+		if err != nil || rec != nil {
+			return err
+		}
+		fmt.Printf("撤销: 恢复 DNS 记录 %s %s %s\n", s.Hostname, s.Record.Type, s.Record.Content)
+		id, err := t.client.CreateDNSRecord(t.ctx, s.ZoneID, stepRecord(s))
+		if err != nil {
+			return err
+		}
+		// 重建的记录 ID 会变化，恢复的配置需要指向新记录
+		for i := range t.j.Before.Routes {
+			if r := &t.j.Before.Routes[i]; r.DNSRecordID == s.RecordID {
+				r.DNSRecordID = id
+			}
+		}
+		s.RecordID = id
+		return nil
+	case config.StepDeleteTunnel:
+		exists, err := t.tunnelExists(s.TunnelID)
+		if err != nil || exists {
+			return err
+		}
+		return fmt.Errorf("隧道 %s 已删除，无法撤销，请运行 cftunnel recover --finish 完成操作", s.TunnelID)
+	case config.StepSaveConfig:
+		// 操作前的配置在回滚结束时统一恢复
+		return nil
+	case config.StepPushIngress:
+		fmt.Println("撤销: 恢复 ingress 配置")
+		return pushIngress(t.client, t.ctx, t.j.Before)
+	case config.StepSetIngress:
+		fmt.Println("撤销: 恢复 ingress 配置")
+		return t.client.PushIngressConfig(t.ctx, t.j.Before.Tunnel.ID, s.PrevIngress)
+	}
+	return fmt.Errorf("未知的操作步骤 %s", s.Action)
+}
+
+// findRecord 按内容查找步骤要创建的记录，用于确认中断前是否已创建
+func (t *txn) findRecord(s *config.JournalStep) (string, error) {
+	records, err := t.client.FindDNSRecords(t.ctx, s.ZoneID, s.Hostname)
+	if err != nil {
+		return "", err
+	}
+	for _, rec := range records {
+		if rec.Type == s.Record.Type && strings.EqualFold(rec.Content, s.Record.Content) {
+			return rec.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func (t *txn) tunnelExists(id string) (bool, error) {
+	tunnels, err := t.client.ListTunnels(t.ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, tun := range tunnels {
+		if tun.ID == id && tun.DeletedAt.IsZero() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func stepRecord(s *config.JournalStep) cfapi.DNSRecord {
+	return cfapi.DNSRecord{Name: s.Hostname, Type: s.Record.Type, Content: s.Record.Content, Proxied: s.Record.Proxied, TTL: s.Record.TTL}
+}
+
+// describeStep 用于进度输出与 recover 展示
+func describeStep(s config.JournalStep) string {
+	switch s.Action {
+	case config.StepCreateRecord:
+		return fmt.Sprintf("创建 DNS 记录 %s → %s", s.Hostname, s.Record.Content)
+	case config.StepDeleteRecord:
+		return fmt.Sprintf("删除 DNS 记录 %s (%s %s)", s.Hostname, s.Record.Type, s.Record.Content)
+	case config.StepDeleteTunnel:
+		return fmt.Sprintf("删除隧道 %s", s.TunnelID)
+	case config.StepSaveConfig:
+		return "保存本地配置"
+	case config.StepPushIngress:
+		return "同步 ingress 配置"
+	case config.StepSetIngress:
+		return fmt.Sprintf("更新 ingress 配置（%d 条规则）", len(s.Ingress))
+	}
+	return s.Action
+}
+
+// 常用步骤
+var (
+	saveConfigStep  = config.JournalStep{Action: config.StepSaveConfig}
+	pushIngressStep = config.JournalStep{Action: config.StepPushIngress}
+)
+
+// createCNAMEStep 创建指向隧道的 CNAME
+func createCNAMEStep(zoneID, hostname, target string) config.JournalStep {
+	return config.JournalStep{
+		Action:   config.StepCreateRecord,
+		ZoneID:   zoneID,
+		Hostname: hostname,
+		Record:   &config.DNSBackup{Type: "CNAME", Content: target, Proxied: true},
+	}
+}
@@ -2,10 +2,10 @@
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
-	"strconv"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/qingchencloud/cftunnel/internal/authproxy"
@@ -23,6 +23,10 @@ func init() {
 var upCmd = &cobra.Command{
 	Use:   "up",
 	Short: "启动隧道",
+	Long: `启动 cloudflared 隧道。
+
+有路由启用密码保护或 IP 规则时，鉴权代理运行在本进程中，up 会保持前台运行：
+按 Ctrl+C 或关闭窗口时一并停止隧道；在其他终端运行 cftunnel down 后 up 自动退出。`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -31,44 +35,67 @@ var upCmd = &cobra.Command{
 		if cfg.Tunnel.Token == "" {
 			return fmt.Errorf("请先运行 cftunnel init && cftunnel create <名称>")
 		}
+		if name := config.ProfileName(); name != config.DefaultProfile {
+			fmt.Printf("Profile: %s\n", name)
+		}
+
+		// 非 HTTP 服务无法经过代理，拒绝启动以免密码保护与 IP 规则被静默忽略
+		for _, r := range cfg.Routes {
+			if hasAccessRules(r) {
+				if err := requireHTTPService(&r); err != nil {
+					return err
+				}
+			}
+		}
 
-		// 为有鉴权配置的路由启动代理
+		// 旧版明文密码一次性迁移为哈希用户并写回配置，避免每次启动重新哈希使会话失效
+		migrated := false
+		for _, r := range cfg.Routes {
+			if r.Auth != nil && r.Auth.Username != "" {
+				if err := migrateLegacyAuth(r.Auth); err != nil {
+					return fmt.Errorf("路由 %s 迁移旧版密码失败: %w", r.Name, err)
+				}
+				migrated = true
+			}
+		}
+
+		// 受保护路由使用持久化的固定代理端口，ingress 只在路由变化时才需要更新
+		changed, err := ensureProxyPorts(cfg)
+		if err != nil {
+			return err
+		}
+		if changed || migrated {
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+		}
+
+		// 为有鉴权配置或 IP 规则的路由启动代理
 		var proxies []*authproxy.Proxy
-		for i, r := range cfg.Routes {
-			if r.Auth == nil {
+		defer func() {
+			for _, p := range proxies {
+				p.Stop()
+			}
+		}()
+		for _, r := range cfg.Routes {
+			if !needsProxy(r) {
 				continue
 			}
-			sigKey, err := hex.DecodeString(r.Auth.SigningKey)
+			pc, err := proxyConfig(r)
 			if err != nil {
-				return fmt.Errorf("路由 %s 的 signing_key 无效: %w", r.Name, err)
-			}
-			port := extractPort(r.Service)
-			if port == "" {
-				return fmt.Errorf("路由 %s 的 service 格式无效: %s", r.Name, r.Service)
+				return fmt.Errorf("路由 %s 的鉴权配置无效: %w", r.Name, err)
 			}
-			proxy, err := authproxy.New(authproxy.Config{
-				Username:   r.Auth.Username,
-				Password:   r.Auth.Password,
-				TargetPort: port,
-				SigningKey: sigKey,
-				CookieTTL:  time.Duration(r.Auth.CookieTTLOrDefault()) * time.Second,
-			})
+			pc.ListenPort = r.ProxyPort
+			proxy, err := authproxy.New(pc)
 			if err != nil {
-				return fmt.Errorf("路由 %s 启动鉴权代理失败: %w", r.Name, err)
+				return fmt.Errorf("路由 %s 启动鉴权代理失败: %w（可在配置中修改 proxy_port）", r.Name, err)
 			}
 			if err := proxy.Start(); err != nil {
 				return fmt.Errorf("路由 %s 启动鉴权代理失败: %w", r.Name, err)
 			}
 			proxies = append(proxies, proxy)
-			proxyPort := strconv.Itoa(proxy.ListenPort())
-			fmt.Printf("鉴权代理已启动: %s → 127.0.0.1:%s → 127.0.0.1:%s\n", r.Hostname, proxyPort, port)
-			cfg.Routes[i].Service = "http://localhost:" + proxyPort
+			fmt.Printf("鉴权代理已启动: %s → 127.0.0.1:%d → %s\n", r.Hostname, r.ProxyPort, r.Service)
 		}
-		defer func() {
-			for _, p := range proxies {
-				p.Stop()
-			}
-		}()
 
 		if len(cfg.Routes) > 0 {
 			client := cfapi.New(cfg.Auth.APIToken, cfg.Auth.AccountID)
@@ -82,14 +109,40 @@ var upCmd = &cobra.Command{
 		// 【删除】自动检查更新逻辑
 		// 删除了关于 cfg.SelfUpdate.AutoCheck 的整个代码块
 
-		return daemon.Start(cfg.Tunnel.Token)
+		if err := daemon.Start(cfg.Tunnel.Token); err != nil {
+			return err
+		}
+		if len(proxies) == 0 {
+			return nil
+		}
+
+		// 鉴权代理运行在当前进程中，up 返回时 defer 会停止代理，而 cloudflared 仍把流量转发到代理端口；
+		// 因此需保持前台运行直到 Ctrl+C，并在退出时一并停止 cloudflared，避免受保护路由失去代理
+		fmt.Println("鉴权代理运行中，按 Ctrl+C 停止隧道")
+		return waitTunnel()
 	},
 }
 
-func extractPort(service string) string {
-	idx := strings.LastIndex(service, ":")
-	if idx < 0 {
-		return ""
+// tunnelWatchInterval 前台运行时检查 cloudflared 是否仍在运行的间隔
+const tunnelWatchInterval = 2 * time.Second
+
+// waitTunnel 等待 Ctrl+C、SIGTERM（Windows 关闭控制台窗口时同样触发）后停止 cloudflared；
+// cloudflared 在其他地方被停止（如 cftunnel down）时直接返回，随后停止鉴权代理
+func waitTunnel() error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	ticker := time.NewTicker(tunnelWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sig:
+			return daemon.Stop()
+		case <-ticker.C:
+			if !daemon.Running() {
+				fmt.Println("隧道已停止，正在关闭鉴权代理")
+				return nil
+			}
+		}
 	}
-	return service[idx+1:]
-}
\ No newline at end of file
+}
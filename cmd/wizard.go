@@ -2,12 +2,10 @@ package cmd
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/huh"
-	"github.com/qingchencloud/cftunnel/internal/authproxy"
 	"github.com/qingchencloud/cftunnel/internal/cfapi"
 	"github.com/qingchencloud/cftunnel/internal/config"
 	"github.com/qingchencloud/cftunnel/internal/daemon"
@@ -19,6 +17,8 @@ var (
 	wizardPort   string
 	wizardAuth   string
 	wizardName   string
+
+	wizardOverwrite, wizardAdopt bool
 )
 
 func init() {
@@ -26,6 +26,8 @@ func init() {
 	wizardCmd.Flags().StringVar(&wizardPort, "port", "", "本地服务端口")
 	wizardCmd.Flags().StringVar(&wizardName, "name", "", "路由名称 (默认使用域名前缀)")
 	wizardCmd.Flags().StringVar(&wizardAuth, "auth", "", "密码保护 (格式: 用户名:密码)")
+	wizardCmd.Flags().BoolVar(&wizardOverwrite, "overwrite", false, "域名已有 A/AAAA/CNAME 记录时替换，原记录备份到配置中，remove 时恢复")
+	wizardCmd.Flags().BoolVar(&wizardAdopt, "adopt", false, "域名已有指向本隧道的 CNAME 时直接接管")
 	rootCmd.AddCommand(wizardCmd)
 }
 
@@ -43,6 +45,9 @@ var wizardCmd = &cobra.Command{
 }
 
 func runWizard(cmd *cobra.Command, args []string) error {
+	if wizardOverwrite && wizardAdopt {
+		return fmt.Errorf("--overwrite 与 --adopt 只能指定一个")
+	}
 	fmt.Println("╔════════════════════════════════════════╗")
 	fmt.Println("║     Cloudflare Tunnel 向导            ║")
 	fmt.Println("╚════════════════════════════════════════╝")
@@ -202,59 +207,58 @@ func runWizard(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("路由 %s 已存在", routeName)
 	}
 
+	for _, r := range cfg.Routes {
+		if strings.EqualFold(r.Hostname, domain) && r.Path == "" {
+			return fmt.Errorf("域名 %s 已被路由 %s 使用", domain, r.Name)
+		}
+	}
+
 	service := "http://localhost:" + port
 
 	fmt.Printf("正在添加路由: %s -> %s\n", domain, service)
 
-	// 查找 Zone
-	zone, err := findZoneForDomain(client, ctx, domain)
+	t, err := beginTxn(client, ctx, "add", routeName, cfg)
 	if err != nil {
 		return err
 	}
 
-	// 创建 DNS CNAME 记录
-	target := cfg.Tunnel.ID + ".cfargotunnel.com"
-	fmt.Printf("正在创建 DNS 记录: %s -> %s\n", domain, target)
-	recordID, err := client.CreateCNAME(ctx, zone.ID, domain, target)
-	if err != nil {
-		return err
-	}
+	route := config.RouteConfig{Name: routeName, Hostname: domain, Service: service}
+	var steps []config.JournalStep
+	if existing := cfg.FindRouteByHostname(domain); existing != nil {
+		// 同一域名已有路由，共用已有的 DNS 记录
+		route.ZoneID, route.DNSRecordID = existing.ZoneID, existing.DNSRecordID
+		fmt.Printf("域名 %s 已由路由 %s 创建 DNS 记录，直接复用\n", domain, existing.Name)
+	} else {
+		// 查找 Zone
+		zone, err := findZoneForDomain(client, ctx, domain)
+		if err != nil {
+			return err
+		}
 
-	// 构建路由配置
-	route := config.RouteConfig{
-		Name:        routeName,
-		Hostname:    domain,
-		Service:     service,
-		ZoneID:      zone.ID,
-		DNSRecordID: recordID,
+		// 创建 CNAME，域名已有记录时按 --adopt / --overwrite 处理
+		target := cfg.Tunnel.ID + ".cfargotunnel.com"
+		route.ZoneID = zone.ID
+		route.DNSRecordID, route.PrevDNS, steps, err = planClaim(client, ctx, zone.ID, domain, target, wizardAdopt, wizardOverwrite)
+		if err != nil {
+			return err
+		}
 	}
 
 	// 密码保护
 	if wizardAuth != "" {
-		user, pass, err := parseAuth(wizardAuth)
+		route.Auth, err = newRouteAuth([]string{wizardAuth})
 		if err != nil {
 			return err
 		}
-		route.Auth = &config.AuthProxy{
-			Username:   user,
-			Password:   pass,
-			SigningKey: hex.EncodeToString(authproxy.RandomKey()),
-		}
 		fmt.Printf("✓ 已启用密码保护: %s\n", wizardAuth)
 	}
 
-	// 保存路由
+	// 创建 DNS 记录、保存路由并推送 ingress，任何一步失败都会回滚
 	cfg.Routes = append(cfg.Routes, route)
-	if err := cfg.Save(); err != nil {
+	if err := t.commit(cfg, append(steps, saveConfigStep, pushIngressStep)...); err != nil {
 		return err
 	}
 
-	// 推送 ingress
-	fmt.Println("正在同步 ingress 配置...")
-	if err := pushIngress(client, ctx, cfg); err != nil {
-		return fmt.Errorf("推送 ingress 失败: %w", err)
-	}
-
 	fmt.Println()
 	fmt.Println("╔════════════════════════════════════════╗")
 	fmt.Println("║            ✅ 全部完成!                 ║")
@@ -0,0 +1,76 @@
+package authproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// accessJWTHeader Cloudflare Access 注入的身份断言头
+const accessJWTHeader = "Cf-Access-Jwt-Assertion"
+
+// accessCookieName 浏览器请求中 Cloudflare Access 的登录 Cookie，与断言头内容相同
+const accessCookieName = "CF_Authorization"
+
+// accessEmailHeader 校验通过后转发给上游的用户邮箱头
+const accessEmailHeader = "Cf-Access-Authenticated-User-Email"
+
+// AccessConfig Cloudflare Access 校验配置
+type AccessConfig struct {
+	TeamDomain string // 团队域名，如 myteam 或 myteam.cloudflareaccess.com
+	Audience   string // Access 应用的 AUD 标签
+}
+
+// accessVerifier 按团队 JWKS 与 AUD 校验 Cloudflare Access 签发的 JWT
+type accessVerifier struct {
+	issuer   string
+	audience string
+	keys     *keySet
+}
+
+func newAccessVerifier(cfg AccessConfig) *accessVerifier {
+	issuer := AccessIssuer(cfg.TeamDomain)
+	return &accessVerifier{
+		issuer:   issuer,
+		audience: cfg.Audience,
+		keys:     newKeySet(issuer+"/cdn-cgi/access/certs", &http.Client{Timeout: 10 * time.Second}),
+	}
+}
+
+// AccessIssuer 将团队名或团队域名规范化为 JWT issuer（https://<团队>.cloudflareaccess.com）
+func AccessIssuer(team string) string {
+	team = strings.TrimSuffix(strings.TrimSpace(team), "/")
+	if strings.HasPrefix(team, "https://") || strings.HasPrefix(team, "http://") {
+		return team
+	}
+	if !strings.Contains(team, ".") {
+		team += ".cloudflareaccess.com"
+	}
+	return "https://" + team
+}
+
+// verify 校验请求携带的 Access JWT，返回用户邮箱（服务令牌返回其 common_name）
+func (a *accessVerifier) verify(ctx context.Context, r *http.Request) (string, error) {
+	token := r.Header.Get(accessJWTHeader)
+	if token == "" {
+		if c, err := r.Cookie(accessCookieName); err == nil {
+			token = c.Value
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("请求未携带 Cloudflare Access 凭据")
+	}
+	claims, err := verifyJWT(ctx, a.keys, token, a.issuer, a.audience)
+	if err != nil {
+		return "", err
+	}
+	if email, _ := claims["email"].(string); email != "" {
+		return email, nil
+	}
+	if cn, _ := claims["common_name"].(string); cn != "" {
+		return cn, nil
+	}
+	return "", fmt.Errorf("Access JWT 中缺少 email")
+}
@@ -0,0 +1,168 @@
+package authproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testAudience = "0123456789abcdef"
+
+// newAccessTestServer 模拟团队域名，在 /cdn-cgi/access/certs 提供 JWKS
+func newAccessTestServer(t *testing.T, signers ...*testSigner) (*httptest.Server, *testJWKS) {
+	t.Helper()
+	jwks := &testJWKS{}
+	jwks.set(signers...)
+	mux := http.NewServeMux()
+	mux.Handle("/cdn-cgi/access/certs", jwks)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, jwks
+}
+
+func accessClaims(issuer string) map[string]any {
+	return map[string]any{
+		"iss":   issuer,
+		"aud":   []string{testAudience},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "alice@example.com",
+	}
+}
+
+func accessRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+	if token != "" {
+		r.Header.Set(accessJWTHeader, token)
+	}
+	return r
+}
+
+func TestAccessVerify(t *testing.T) {
+	signer := newTestSigner(t, "k1")
+	srv, _ := newAccessTestServer(t, signer)
+	a := newAccessVerifier(AccessConfig{TeamDomain: srv.URL, Audience: testAudience})
+
+	with := func(k string, v any) string {
+		c := accessClaims(srv.URL)
+		c[k] = v
+		return signer.sign(t, c)
+	}
+	cases := []struct {
+		name  string
+		token string
+		want  string // 为空表示应拒绝
+	}{
+		{"有效", signer.sign(t, accessClaims(srv.URL)), "alice@example.com"},
+		{"缺少邮箱", with("email", nil), ""},
+		{"服务令牌 common_name", func() string {
+			c := accessClaims(srv.URL)
+			delete(c, "email")
+			c["common_name"] = "ci.access"
+			return signer.sign(t, c)
+		}(), "ci.access"},
+		{"AUD 不匹配", with("aud", []string{"another-app"}), ""},
+		{"issuer 不匹配", with("iss", "https://other.cloudflareaccess.com"), ""},
+		{"已过期", with("exp", time.Now().Add(-2*jwtLeeway).Unix()), ""},
+		{"缺少凭据", "", ""},
+	}
+	for _, c := range cases {
+		got, err := a.verify(t.Context(), accessRequest(c.token))
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("%s: 应拒绝，实际通过为 %s", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: 期望 %s，实际 %s (%v)", c.name, c.want, got, err)
+		}
+	}
+}
+
+func TestAccessVerifyCookie(t *testing.T) {
+	signer := newTestSigner(t, "k1")
+	srv, _ := newAccessTestServer(t, signer)
+	a := newAccessVerifier(AccessConfig{TeamDomain: srv.URL, Audience: testAudience})
+
+	r := accessRequest("")
+	r.AddCookie(&http.Cookie{Name: accessCookieName, Value: signer.sign(t, accessClaims(srv.URL))})
+	if got, err := a.verify(t.Context(), r); err != nil || got != "alice@example.com" {
+		t.Fatalf("应接受 CF_Authorization Cookie: %s (%v)", got, err)
+	}
+}
+
+func TestAccessProxyRejectsMissingAssertion(t *testing.T) {
+	signer := newTestSigner(t, "k1")
+	srv, _ := newAccessTestServer(t, signer)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(accessEmailHeader)))
+	}))
+	t.Cleanup(upstream.Close)
+	p, err := New(Config{
+		Mode:       ModeAccess,
+		Access:     &AccessConfig{TeamDomain: srv.URL, Audience: testAudience},
+		SigningKey: RandomKey(),
+		Upstream:   upstream.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.listener.Close() })
+
+	if w := serve(p, accessRequest("")); w.Code != http.StatusForbidden {
+		t.Fatalf("缺少 %s 应拒绝: %d", accessJWTHeader, w.Code)
+	}
+	w := serve(p, accessRequest(signer.sign(t, accessClaims(srv.URL))))
+	if w.Code != http.StatusOK || w.Body.String() != "alice@example.com" {
+		t.Fatalf("有效凭据应转发并带上邮箱: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAccessKeyRotation(t *testing.T) {
+	oldKey, newKey := newTestSigner(t, "k1"), newTestSigner(t, "k2")
+	srv, jwks := newAccessTestServer(t, oldKey)
+	a := newAccessVerifier(AccessConfig{TeamDomain: srv.URL, Audience: testAudience})
+	verify := func(s *testSigner) error {
+		_, err := a.verify(t.Context(), accessRequest(s.sign(t, accessClaims(srv.URL))))
+		return err
+	}
+
+	if err := verify(oldKey); err != nil {
+		t.Fatal(err)
+	}
+	if n := jwks.count(); n != 1 {
+		t.Fatalf("首次校验应拉取一次 JWKS，实际 %d", n)
+	}
+
+	// 团队轮换密钥：刚刷新过，未知 kid 不得立即触发新的请求
+	jwks.set(oldKey, newKey)
+	if err := verify(newKey); err == nil {
+		t.Fatal("最小刷新间隔内不应拉取新密钥")
+	}
+	if n := jwks.count(); n != 1 {
+		t.Fatalf("最小刷新间隔内不应重复拉取 JWKS，实际 %d 次", n)
+	}
+
+	// 超过最小间隔后，未知 kid 只触发一次刷新
+	a.keys.mu.Lock()
+	a.keys.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	a.keys.mu.Unlock()
+	if err := verify(newKey); err != nil {
+		t.Fatalf("刷新后应识别新密钥: %v", err)
+	}
+	if err := verify(newKey); err != nil {
+		t.Fatal(err)
+	}
+	if n := jwks.count(); n != 2 {
+		t.Fatalf("未知 kid 应只触发一次刷新，实际共拉取 %d 次", n)
+	}
+
+	// 伪造的 kid 在最小间隔内同样不会触发请求
+	if err := verify(newTestSigner(t, "forged")); err == nil {
+		t.Fatal("未知 kid 应拒绝")
+	}
+	if n := jwks.count(); n != 2 {
+		t.Fatalf("伪造 kid 不应触发刷新，实际共拉取 %d 次", n)
+	}
+}
@@ -0,0 +1,109 @@
+package authproxy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// apiKeyPrefix 生成的 API Key 前缀，用于区分应用自身的 Bearer 令牌
+const apiKeyPrefix = "cft_"
+
+// APIKey 机器客户端使用的静态密钥，只保存 SHA-256 哈希
+type APIKey struct {
+	Name string
+	Hash string
+}
+
+// GenerateAPIKey 生成新的 API Key，返回明文（仅展示一次）与哈希
+func GenerateAPIKey() (string, string) {
+	key := apiKeyPrefix + randomToken()
+	return key, HashAPIKey(key)
+}
+
+// HashAPIKey 计算 API Key 的哈希（密钥本身为高熵随机串，无需慢哈希）
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// machineAuth 校验 API Key 或 Basic 凭据，返回通过校验的身份；attempted 表示请求携带了本代理的凭据
+// 校验通过后会移除凭据头，避免泄露给上游
+func (p *Proxy) machineAuth(r *http.Request) (id *identity, attempted bool) {
+	if key, header := p.requestAPIKey(r); key != "" {
+		ipKey := "ip=" + ClientIP(r)
+		if locked, _ := p.limiter.locked(ipKey); locked {
+			return nil, true
+		}
+		name := p.matchAPIKey(key)
+		if name == "" {
+			p.limiter.fail(ipKey)
+			return nil, true
+		}
+		r.Header.Del(header)
+		return &identity{User: "apikey:" + name}, true
+	}
+
+	username, password, hasBasic := r.BasicAuth()
+	if !hasBasic || p.cfg.Mode != ModePassword {
+		return nil, false
+	}
+	ipKey, userKey := "ip="+ClientIP(r), "user="+username
+	if locked, _ := p.limiter.locked(ipKey, userKey); locked {
+		return nil, true
+	}
+	user, exists := p.users[username]
+	hash := user.PasswordHash
+	if !exists {
+		hash = dummyHash()
+	}
+	// 启用两步验证的用户无法通过 Basic 提供动态码，一律拒绝
+	if !VerifyPassword(hash, password) || !exists || user.TOTPSecret != "" {
+		p.limiter.fail(ipKey, userKey)
+		return nil, true
+	}
+	p.limiter.reset(ipKey, userKey)
+	r.Header.Del("Authorization")
+	return &identity{User: username}, true
+}
+
+// requestAPIKey 从自定义头或 Authorization: Bearer 中读取 API Key，返回密钥及其所在的头
+func (p *Proxy) requestAPIKey(r *http.Request) (string, string) {
+	if len(p.cfg.APIKeys) == 0 {
+		return "", ""
+	}
+	if h := p.cfg.APIKeyHeader; h != "" {
+		if key := strings.TrimSpace(r.Header.Get(h)); key != "" {
+			return key, h
+		}
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		if strings.HasPrefix(token, apiKeyPrefix) {
+			return token, "Authorization"
+		}
+	}
+	return "", ""
+}
+
+// matchAPIKey 返回匹配的 API Key 名称，逐个常量时间比较
+func (p *Proxy) matchAPIKey(key string) string {
+	hash := []byte(HashAPIKey(key))
+	name := ""
+	for _, k := range p.cfg.APIKeys {
+		if subtle.ConstantTimeCompare(hash, []byte(k.Hash)) == 1 {
+			name = k.Name
+		}
+	}
+	return name
+}
+
+// unauthorized 机器客户端凭据无效时返回 401
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="cftunnel"`)
+	}
+	http.Error(w, "凭据无效", http.StatusUnauthorized)
+}
@@ -0,0 +1,96 @@
+package authproxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IdentityHeaders 转发给上游的身份头名称，某一项为空时不发送
+type IdentityHeaders struct {
+	User   string
+	Email  string
+	Groups string
+}
+
+// DefaultIdentityHeaders 未配置时使用的身份头，与 oauth2-proxy 等网关保持一致
+var DefaultIdentityHeaders = IdentityHeaders{
+	User:   "X-Forwarded-User",
+	Email:  "X-Forwarded-Email",
+	Groups: "X-Auth-Request-Groups",
+}
+
+// identity 已认证的访问者身份
+type identity struct {
+	User   string
+	Email  string
+	Groups []string
+}
+
+// sessionIdentity 从会话还原身份，OIDC 会话的用户名即邮箱
+func (p *Proxy) sessionIdentity(sess *Session) *identity {
+	id := &identity{User: sess.Username, Groups: sess.Groups}
+	if p.cfg.Mode == ModeOIDC || strings.Contains(sess.Username, "@") {
+		id.Email = sess.Username
+	}
+	return id
+}
+
+// forward 清理客户端伪造的身份头与本代理的 Cookie，注入已验证的身份后转发给上游
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, id *identity) {
+	h := p.identityHeaders()
+	for _, name := range []string{
+		h.User, h.Email, h.Groups,
+		DefaultIdentityHeaders.User, DefaultIdentityHeaders.Email, DefaultIdentityHeaders.Groups,
+		accessEmailHeader,
+	} {
+		if name != "" {
+			r.Header.Del(name)
+		}
+	}
+	stripProxyCookies(r)
+
+	if id != nil {
+		if h.User != "" && id.User != "" {
+			r.Header.Set(h.User, id.User)
+		}
+		if h.Email != "" && id.Email != "" {
+			r.Header.Set(h.Email, id.Email)
+		}
+		if h.Groups != "" && len(id.Groups) > 0 {
+			r.Header.Set(h.Groups, strings.Join(id.Groups, ","))
+		}
+		if p.cfg.Mode == ModeAccess {
+			r.Header.Set(accessEmailHeader, id.Email)
+		}
+	}
+	p.reverse.ServeHTTP(w, r)
+}
+
+func (p *Proxy) identityHeaders() IdentityHeaders {
+	if p.cfg.IdentityHeaders == nil {
+		return DefaultIdentityHeaders
+	}
+	return *p.cfg.IdentityHeaders
+}
+
+// stripProxyCookies 从 Cookie 头中移除本代理使用的 __cftunnel_ Cookie，其余原样保留
+func stripProxyCookies(r *http.Request) {
+	lines := r.Header.Values("Cookie")
+	if len(lines) == 0 {
+		return
+	}
+	var kept []string
+	for _, line := range lines {
+		for _, part := range strings.Split(line, ";") {
+			part = strings.TrimSpace(part)
+			if part == "" || strings.HasPrefix(part, "__cftunnel_") {
+				continue
+			}
+			kept = append(kept, part)
+		}
+	}
+	r.Header.Del("Cookie")
+	if len(kept) > 0 {
+		r.Header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
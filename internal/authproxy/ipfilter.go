@@ -0,0 +1,78 @@
+package authproxy
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ipFilter 按 CIDR 列表放行或拒绝客户端，拒绝列表优先
+type ipFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+func newIPFilter(allow, deny []string) (*ipFilter, error) {
+	a, err := ParsePrefixes(allow)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParsePrefixes(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFilter{allow: a, deny: d}, nil
+}
+
+// enabled 是否配置了任何 IP 规则
+func (f *ipFilter) enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// permit 判断客户端 IP 是否允许访问；配置了允许列表时只放行列表内的地址
+func (f *ipFilter) permit(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return !f.enabled()
+	}
+	addr = addr.Unmap()
+	for _, p := range f.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, p := range f.allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrefixes 解析 CIDR 列表，单个 IP 视为 /32 或 /128
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", s)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 CIDR: %s", s)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package authproxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// RetiredKey 轮换下来的旧签名密钥，到期前仍可校验已签发的 Cookie，但不再用于签发
+type RetiredKey struct {
+	Key     []byte
+	Expires time.Time
+}
+
+// KeyID 返回签名密钥的 ID（密钥 SHA-256 的前 8 位十六进制），写入 Cookie 用于选择校验密钥
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// verifyKey 按 ID 查找可用于校验的密钥，已过期的旧密钥视为不存在
+func (p *Proxy) verifyKey(kid string) ([]byte, bool) {
+	if kid == p.keyID {
+		return p.cfg.SigningKey, true
+	}
+	for _, k := range p.cfg.RetiredKeys {
+		if KeyID(k.Key) == kid && time.Now().Before(k.Expires) {
+			return k.Key, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,107 @@
+package authproxy
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	limiterFreeAttempts = 5                // 连续失败多少次后开始退避
+	limiterMaxLockout   = 15 * time.Minute // 单次锁定时长上限
+	limiterForget       = time.Hour        // 无失败记录多久后清除
+)
+
+// attempt 单个 IP 或用户名的失败记录
+type attempt struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// loginLimiter 按客户端 IP 和用户名分别统计登录失败次数，失败过多时指数退避锁定
+type loginLimiter struct {
+	mu       sync.Mutex
+	attempts map[string]*attempt
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{attempts: make(map[string]*attempt)}
+}
+
+// locked 返回任一 key 是否处于锁定中及剩余时长
+func (l *loginLimiter) locked(keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range keys {
+		if a, ok := l.attempts[k]; ok && now.Before(a.lockedUntil) {
+			if d := a.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait > 0, wait
+}
+
+// fail 记录一次失败，超过免费次数后锁定 2^n 秒（上限 limiterMaxLockout）
+func (l *loginLimiter) fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	for _, k := range keys {
+		a, ok := l.attempts[k]
+		if !ok {
+			a = &attempt{}
+			l.attempts[k] = a
+		}
+		a.failures++
+		a.lastFailure = now
+		if a.failures <= limiterFreeAttempts {
+			continue
+		}
+		lockout := limiterMaxLockout
+		if shift := a.failures - limiterFreeAttempts - 1; shift < 10 {
+			lockout = min(time.Second<<shift, limiterMaxLockout)
+		}
+		a.lockedUntil = now.Add(lockout)
+		log.Printf("[authproxy] 登录失败次数过多，已锁定 %s %s（连续失败 %d 次）", k, lockout, a.failures)
+	}
+}
+
+// reset 登录成功后清除记录
+func (l *loginLimiter) reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		delete(l.attempts, k)
+	}
+}
+
+// sweep 清理长时间没有失败的记录，防止内存无限增长
+func (l *loginLimiter) sweep(now time.Time) {
+	for k, a := range l.attempts {
+		if now.Sub(a.lastFailure) > limiterForget && now.After(a.lockedUntil) {
+			delete(l.attempts, k)
+		}
+	}
+}
+
+// ClientIP 返回真实客户端 IP：请求来自本机 cloudflared 时信任 CF-Connecting-IP
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if cf := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); net.ParseIP(cf) != nil {
+			return cf
+		}
+	}
+	return host
+}
@@ -0,0 +1,239 @@
+package authproxy
+
+import (
+	"bytes"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:embed login.html
+var loginHTML string
+
+var loginTmpl = template.Must(template.New("login").Parse(loginHTML))
+
+//go:embed logout.html
+var logoutHTML string
+
+var logoutTmpl = template.Must(template.New("logout").Parse(logoutHTML))
+
+const csrfCookieName = "__cftunnel_csrf"
+
+// csrfError CSRF 校验失败时登录页显示的提示
+const csrfError = "页面已过期，请重新提交"
+
+// stateTTL 登录页 state 参数（登录后返回地址）的有效期
+const stateTTL = 30 * time.Minute
+
+// loginPage 登录页模板数据，自定义模板可使用全部字段
+type loginPage struct {
+	Route    string       // 路由名称
+	Hostname string       // 访问域名
+	Title    string       // 自定义标题
+	Logo     template.URL // 自定义 Logo 地址（本地图片已内联为 data URI）
+	MFA      bool         // 是否显示动态码输入框
+	Error    string       // 错误提示
+	State    string       // 签名后的返回地址，随表单提交
+	CSRF     string       // 双重提交 CSRF 令牌
+}
+
+// LoginBranding 登录页定制：自定义模板、标题与 Logo
+type LoginBranding struct {
+	Template string // html/template 模板文件路径，为空使用内置页面
+	Title    string
+	Logo     string // 图片 URL 或本地图片文件路径
+}
+
+// ParseLoginTemplate 解析自定义登录页模板
+func ParseLoginTemplate(file string) (*template.Template, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取登录页模板失败: %w", err)
+	}
+	t, err := template.New("login").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析登录页模板失败: %w", err)
+	}
+	return t, nil
+}
+
+// LoadLogo 将 Logo 配置转换为可直接用于 <img src> 的地址，本地文件内联为 data URI
+func LoadLogo(logo string) (template.URL, error) {
+	if logo == "" || strings.HasPrefix(logo, "https://") || strings.HasPrefix(logo, "http://") || strings.HasPrefix(logo, "data:image/") {
+		return template.URL(logo), nil
+	}
+	data, err := os.ReadFile(logo)
+	if err != nil {
+		return "", fmt.Errorf("读取 Logo 失败: %w", err)
+	}
+	mime := http.DetectContentType(data)
+	if strings.HasSuffix(strings.ToLower(logo), ".svg") {
+		mime = "image/svg+xml"
+	}
+	if !strings.HasPrefix(mime, "image/") {
+		return "", fmt.Errorf("Logo 文件 %s 不是图片", logo)
+	}
+	return template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// newLoginPage 按路由定制项准备登录页模板与公共字段
+func newLoginPage(cfg Config) (*template.Template, loginPage, error) {
+	page := loginPage{Route: cfg.Route, Hostname: cfg.Hostname}
+	tmpl := loginTmpl
+	b := cfg.Branding
+	if b == nil {
+		return tmpl, page, nil
+	}
+	if b.Template != "" {
+		t, err := ParseLoginTemplate(b.Template)
+		if err != nil {
+			return nil, page, err
+		}
+		tmpl = t
+	}
+	logo, err := LoadLogo(b.Logo)
+	if err != nil {
+		return nil, page, err
+	}
+	page.Title, page.Logo = b.Title, logo
+	return tmpl, page, nil
+}
+
+// renderLogin 以指定状态码输出登录页，并确保客户端持有 CSRF Cookie
+func (p *Proxy) renderLogin(w http.ResponseWriter, r *http.Request, status int, page loginPage) {
+	page.Route, page.Title, page.Logo = p.page.Route, p.page.Title, p.page.Logo
+	page.Hostname = p.page.Hostname
+	if page.Hostname == "" {
+		page.Hostname = r.Host
+	}
+	page.CSRF = csrfToken(w, r)
+
+	var buf bytes.Buffer
+	if err := p.loginTmpl.Execute(&buf, page); err != nil {
+		log.Printf("[authproxy] 渲染登录页失败: %v", err)
+		http.Error(w, "登录页渲染失败", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// renderLogout 以指定状态码输出注销确认页，表单携带 CSRF 令牌以防跨站请求强制注销
+func (p *Proxy) renderLogout(w http.ResponseWriter, r *http.Request, status int) {
+	page := loginPage{Title: p.page.Title, Logo: p.page.Logo, CSRF: csrfToken(w, r)}
+	var buf bytes.Buffer
+	if err := logoutTmpl.Execute(&buf, page); err != nil {
+		log.Printf("[authproxy] 渲染注销页失败: %v", err)
+		http.Error(w, "注销页渲染失败", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// loginPageFromQuery 根据 /___auth/login 的查询参数还原登录页状态
+func loginPageFromQuery(r *http.Request) loginPage {
+	q := r.URL.Query()
+	page := loginPage{MFA: q.Has("mfa"), State: q.Get("state")}
+	switch q.Get("error") {
+	case "":
+	case "locked":
+		page.Error = "尝试次数过多，请稍后再试"
+	default:
+		if page.MFA {
+			page.Error = "动态码错误或已过期"
+		} else {
+			page.Error = "用户名或密码错误"
+		}
+	}
+	return page
+}
+
+// loginURL 构造登录页地址，kv 为额外的查询参数键值对
+func loginURL(state string, kv ...string) string {
+	q := url.Values{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		q.Set(kv[i], kv[i+1])
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	return loginPath + "?" + q.Encode()
+}
+
+// signState 将登录后要返回的地址签名为 state 参数，防止被篡改为外部地址
+func (p *Proxy) signState(rd string) string {
+	expiry := time.Now().Add(stateTTL).Unix()
+	payload := fmt.Sprintf("%s:%x", base64.RawURLEncoding.EncodeToString([]byte(rd)), expiry)
+	return payload + "." + signPayload(p.stateKey, payload)
+}
+
+// returnTo 校验 state 并返回登录后的跳转地址，无效时返回 /
+func (p *Proxy) returnTo(state string) string {
+	v, ok := verifyToken(p.stateKey, state)
+	if !ok {
+		return "/"
+	}
+	rd, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return "/"
+	}
+	return safeReturnPath(string(rd))
+}
+
+// safeReturnPath 只允许站内相对路径，拒绝 //evil.com 之类的跳转
+func safeReturnPath(rd string) string {
+	if !strings.HasPrefix(rd, "/") || strings.HasPrefix(rd, "//") || strings.HasPrefix(rd, "/\\") {
+		return "/"
+	}
+	if strings.HasPrefix(rd, authPathPrefix) {
+		return "/"
+	}
+	return rd
+}
+
+// requestReturnPath 未登录请求登录后应返回的地址，仅页面请求保留原路径
+func requestReturnPath(r *http.Request) string {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "/"
+	}
+	return safeReturnPath(r.URL.RequestURI())
+}
+
+// csrfToken 返回客户端的 CSRF 令牌，不存在时生成并写入 Cookie
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookieName); err == nil && len(c.Value) >= 32 {
+		return c.Value
+	}
+	token := randomToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// checkCSRF 双重提交校验：表单中的令牌必须与 Cookie 一致，跨站表单无法读取 Cookie
+func checkCSRF(r *http.Request) bool {
+	c, err := r.Cookie(csrfCookieName)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(r.PostFormValue("csrf"))) == 1
+}
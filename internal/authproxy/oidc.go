@@ -0,0 +1,270 @@
+package authproxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const oidcCookieName = "__cftunnel_oidc"
+const callbackPath = "/___auth/callback"
+
+// oidcStateTTL 跳转到身份提供方后完成登录的有效期
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCConfig OpenID Connect 登录配置
+type OIDCConfig struct {
+	Issuer         string
+	ClientID       string
+	ClientSecret   string   // 为空时按公共客户端处理（仅依赖 PKCE）
+	RedirectURL    string   // 为空时使用 https://<请求域名>/___auth/callback
+	Scopes         []string // 默认 openid email profile
+	GroupsClaim    string   // 默认 groups
+	AllowedEmails  []string
+	AllowedDomains []string
+	AllowedGroups  []string
+}
+
+// oidcProvider 延迟加载的身份提供方元数据
+type oidcProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	authURL  string
+	tokenURL string
+	keys     *keySet
+}
+
+func newOIDCProvider(cfg OIDCConfig) *oidcProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &oidcProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover 读取 issuer 的 .well-known/openid-configuration，成功后缓存
+func (o *oidcProvider) discover(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.keys != nil {
+		return nil
+	}
+
+	u := strings.TrimSuffix(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("获取 OIDC 配置失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取 OIDC 配置失败: HTTP %d", resp.StatusCode)
+	}
+	var meta struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return fmt.Errorf("解析 OIDC 配置失败: %w", err)
+	}
+	if meta.Issuer != o.cfg.Issuer {
+		return fmt.Errorf("OIDC issuer 不匹配: 配置为 %s，实际为 %s", o.cfg.Issuer, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return fmt.Errorf("OIDC 配置缺少必要的 endpoint")
+	}
+	o.authURL = meta.AuthorizationEndpoint
+	o.tokenURL = meta.TokenEndpoint
+	o.keys = newKeySet(meta.JWKSURI, o.client)
+	return nil
+}
+
+func (o *oidcProvider) redirectURL(r *http.Request) string {
+	if o.cfg.RedirectURL != "" {
+		return o.cfg.RedirectURL
+	}
+	return "https://" + r.Host + callbackPath
+}
+
+// exchange 用授权码换取 id_token 并校验，返回 claims
+func (o *oidcProvider) exchange(ctx context.Context, r *http.Request, code, verifier, nonce string) (map[string]any, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL(r))
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", o.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("换取 token 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("换取 token 失败: HTTP %d", resp.StatusCode)
+	}
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.IDToken == "" {
+		return nil, fmt.Errorf("token 响应中缺少 id_token")
+	}
+
+	claims, err := verifyJWT(ctx, o.keys, tok.IDToken, o.cfg.Issuer, o.cfg.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("id_token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+// authorize 按允许列表校验用户，返回用于签发 Cookie 的邮箱
+func (o *oidcProvider) authorize(claims map[string]any) (string, error) {
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return "", fmt.Errorf("id_token 中缺少 email")
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return "", fmt.Errorf("邮箱 %s 未验证", email)
+	}
+	email = strings.ToLower(email)
+
+	for _, e := range o.cfg.AllowedEmails {
+		if strings.EqualFold(e, email) {
+			return email, nil
+		}
+	}
+	for _, d := range o.cfg.AllowedDomains {
+		if strings.HasSuffix(email, "@"+strings.ToLower(strings.TrimPrefix(d, "@"))) {
+			return email, nil
+		}
+	}
+	groups := claimStrings(claims, o.cfg.GroupsClaim)
+	for _, g := range o.cfg.AllowedGroups {
+		for _, ug := range groups {
+			if g == ug {
+				return email, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("用户 %s 不在允许列表中", email)
+}
+
+// startOIDC 生成 state/nonce/PKCE 并跳转到身份提供方
+func (p *Proxy) startOIDC(w http.ResponseWriter, r *http.Request) {
+	if err := p.oidc.discover(r.Context()); err != nil {
+		log.Printf("[authproxy] %v", err)
+		http.Error(w, "身份提供方暂时不可用", http.StatusBadGateway)
+		return
+	}
+
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	expiry := time.Now().Add(oidcStateTTL).Unix()
+	rd := base64.RawURLEncoding.EncodeToString([]byte(requestReturnPath(r)))
+	payload := fmt.Sprintf("%s:%s:%s:%s:%x", state, nonce, verifier, rd, expiry)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    payload + "." + signPayload(p.oidcKey, payload),
+		Path:     "/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.oidc.cfg.ClientID)
+	q.Set("redirect_uri", p.oidc.redirectURL(r))
+	q.Set("scope", strings.Join(p.oidc.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.oidc.authURL, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, p.oidc.authURL+sep+q.Encode(), http.StatusFound)
+}
+
+// handleCallback 处理身份提供方回调，校验通过后签发鉴权 Cookie
+func (p *Proxy) handleCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		http.Error(w, "登录状态已失效，请重新访问", http.StatusBadRequest)
+		return
+	}
+	value, ok := verifyToken(p.oidcKey, cookie.Value)
+	parts := strings.Split(value, ":")
+	if !ok || len(parts) != 4 || r.URL.Query().Get("state") != parts[0] {
+		http.Error(w, "登录状态无效，请重新访问", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Value: "", Path: "/", MaxAge: -1})
+
+	if e := r.URL.Query().Get("error"); e != "" {
+		http.Error(w, "身份提供方拒绝登录: "+e, http.StatusForbidden)
+		return
+	}
+	if err := p.oidc.discover(r.Context()); err != nil {
+		log.Printf("[authproxy] %v", err)
+		http.Error(w, "身份提供方暂时不可用", http.StatusBadGateway)
+		return
+	}
+	claims, err := p.oidc.exchange(r.Context(), r, r.URL.Query().Get("code"), parts[2], parts[1])
+	if err != nil {
+		log.Printf("[authproxy] OIDC 登录失败: %v", err)
+		http.Error(w, "登录失败", http.StatusUnauthorized)
+		return
+	}
+	email, err := p.oidc.authorize(claims)
+	if err != nil {
+		log.Printf("[authproxy] OIDC 登录被拒绝: %v", err)
+		http.Error(w, "无权访问此服务", http.StatusForbidden)
+		return
+	}
+
+	p.issueCookie(w, r, email, claimStrings(claims, p.oidc.cfg.GroupsClaim))
+	rd, _ := base64.RawURLEncoding.DecodeString(parts[3])
+	http.Redirect(w, r, safeReturnPath(string(rd)), http.StatusSeeOther)
+}
+
+// randomToken 生成 URL 安全的随机字符串
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,360 @@
+package authproxy
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSigner 测试用的 RSA 签名密钥
+type testSigner struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T, kid string) *testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{kid: kid, key: key}
+}
+
+// sign 生成 RS256 JWT
+func (s *testSigner) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := enc(map[string]string{"alg": "RS256", "kid": s.kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (s *testSigner) jwk() map[string]string {
+	pub := s.key.PublicKey
+	return map[string]string{
+		"kid": s.kid,
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// testJWKS 可在运行中更换密钥的 JWKS 服务，并统计被请求的次数
+type testJWKS struct {
+	mu      sync.Mutex
+	signers []*testSigner
+	fetches int
+}
+
+func (j *testJWKS) set(signers ...*testSigner) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.signers = signers
+}
+
+func (j *testJWKS) count() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.fetches
+}
+
+func (j *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fetches++
+	var keys []map[string]string
+	for _, s := range j.signers {
+		keys = append(keys, s.jwk())
+	}
+	json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
+
+// mockIssuer 本地 OIDC 身份提供方：discovery、JWKS 与 token endpoint
+type mockIssuer struct {
+	*httptest.Server
+	signer *testSigner
+	jwks   *testJWKS
+
+	mu        sync.Mutex
+	challenge string         // 授权请求中的 code_challenge
+	claims    map[string]any // 下一次签发的 id_token 内容，nonce 为空时使用授权请求中的 nonce
+	nonce     string
+}
+
+const testClientID = "cftunnel-test"
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	m := &mockIssuer{signer: newTestSigner(t, "k1"), jwks: &testJWKS{}}
+	m.jwks.set(m.signer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.Handle("/jwks", m.jwks)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		verifier := r.PostFormValue("code_verifier")
+		sum := sha256.Sum256([]byte(verifier))
+		if r.PostFormValue("code") != "good-code" || r.PostFormValue("client_id") != testClientID ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		claims := map[string]any{"nonce": m.nonce}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.signer.sign(t, claims)})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// validClaims 返回可以通过校验的 id_token 内容
+func (m *mockIssuer) validClaims(email string) map[string]any {
+	return map[string]any{
+		"iss":            m.URL,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"email":          email,
+		"email_verified": true,
+		"groups":         []string{"dev"},
+	}
+}
+
+func newOIDCTestProxy(t *testing.T, m *mockIssuer, cfg OIDCConfig) *Proxy {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.Header.Get(DefaultIdentityHeaders.Email)))
+	}))
+	t.Cleanup(upstream.Close)
+	cfg.Issuer, cfg.ClientID = m.URL, testClientID
+	p, err := New(Config{Mode: ModeOIDC, OIDC: &cfg, SigningKey: RandomKey(), Upstream: upstream.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.listener.Close() })
+	return p
+}
+
+func serve(p *Proxy, r *http.Request, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	for _, c := range cookies {
+		if c != nil {
+			r.AddCookie(c)
+		}
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	return w
+}
+
+func findCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name && c.MaxAge >= 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// startLogin 访问受保护页面，返回跳转到身份提供方的查询参数与 state Cookie
+func startLogin(t *testing.T, p *Proxy, m *mockIssuer, path string) (url.Values, *http.Cookie) {
+	t.Helper()
+	w := serve(p, httptest.NewRequest(http.MethodGet, "https://app.example.com"+path, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("未跳转到身份提供方: %d", w.Code)
+	}
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(loc.String(), m.URL+"/authorize?") {
+		t.Fatalf("跳转地址错误: %s", w.Header().Get("Location"))
+	}
+	q := loc.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("授权请求缺少 PKCE/state/nonce: %v", q)
+	}
+	m.mu.Lock()
+	m.challenge, m.nonce = q.Get("code_challenge"), q.Get("nonce")
+	m.mu.Unlock()
+	c := findCookie(w, oidcCookieName)
+	if c == nil {
+		t.Fatal("未设置 OIDC state Cookie")
+	}
+	return q, c
+}
+
+func callback(p *Proxy, state string, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+	u := "https://app.example.com" + callbackPath + "?code=good-code&state=" + url.QueryEscape(state)
+	return serve(p, httptest.NewRequest(http.MethodGet, u, nil), stateCookie)
+}
+
+func TestOIDCLoginRoundTrip(t *testing.T) {
+	m := newMockIssuer(t)
+	m.claims = m.validClaims("alice@example.com")
+	p := newOIDCTestProxy(t, m, OIDCConfig{AllowedEmails: []string{"Alice@example.com"}})
+
+	q, stateCookie := startLogin(t, p, m, "/app/page?x=1")
+	w := callback(p, q.Get("state"), stateCookie)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/app/page?x=1" {
+		t.Fatalf("回调应跳回原地址: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	auth := findCookie(w, cookieName)
+	if auth == nil {
+		t.Fatal("登录后未签发鉴权 Cookie")
+	}
+
+	w = serve(p, httptest.NewRequest(http.MethodGet, "https://app.example.com/app/page", nil), auth)
+	if w.Code != http.StatusOK || w.Body.String() != "hello alice@example.com" {
+		t.Fatalf("登录后访问失败: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	m := newMockIssuer(t)
+	m.claims = m.validClaims("alice@example.com")
+	p := newOIDCTestProxy(t, m, OIDCConfig{AllowedEmails: []string{"alice@example.com"}})
+
+	_, stateCookie := startLogin(t, p, m, "/")
+	if w := callback(p, "forged", stateCookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("state 不匹配应拒绝: %d", w.Code)
+	}
+	q, _ := startLogin(t, p, m, "/")
+	if w := callback(p, q.Get("state"), nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("缺少 state Cookie 应拒绝: %d", w.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsPKCEAndNonceMismatch(t *testing.T) {
+	m := newMockIssuer(t)
+	m.claims = m.validClaims("alice@example.com")
+	p := newOIDCTestProxy(t, m, OIDCConfig{AllowedEmails: []string{"alice@example.com"}})
+
+	q, stateCookie := startLogin(t, p, m, "/")
+	m.mu.Lock()
+	m.challenge = "not-the-challenge"
+	m.mu.Unlock()
+	if w := callback(p, q.Get("state"), stateCookie); w.Code != http.StatusUnauthorized {
+		t.Fatalf("code_verifier 不匹配应拒绝: %d", w.Code)
+	}
+
+	q, stateCookie = startLogin(t, p, m, "/")
+	m.mu.Lock()
+	m.nonce = "replayed-nonce"
+	m.mu.Unlock()
+	if w := callback(p, q.Get("state"), stateCookie); w.Code != http.StatusUnauthorized {
+		t.Fatalf("nonce 不匹配应拒绝: %d", w.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsUnlistedUser(t *testing.T) {
+	m := newMockIssuer(t)
+	m.claims = m.validClaims("mallory@evil.com")
+	p := newOIDCTestProxy(t, m, OIDCConfig{AllowedDomains: []string{"example.com"}})
+
+	q, stateCookie := startLogin(t, p, m, "/")
+	w := callback(p, q.Get("state"), stateCookie)
+	if w.Code != http.StatusForbidden || findCookie(w, cookieName) != nil {
+		t.Fatalf("不在允许列表中的用户应拒绝: %d", w.Code)
+	}
+}
+
+func TestOIDCAuthorize(t *testing.T) {
+	o := newOIDCProvider(OIDCConfig{
+		AllowedEmails:  []string{"Boss@Corp.com"},
+		AllowedDomains: []string{"@example.com"},
+		AllowedGroups:  []string{"admins"},
+	})
+	cases := []struct {
+		name   string
+		claims map[string]any
+		want   string // 为空表示应拒绝
+	}{
+		{"邮箱白名单（忽略大小写）", map[string]any{"email": "boss@corp.com"}, "boss@corp.com"},
+		{"域名白名单", map[string]any{"email": "Alice@Example.com", "email_verified": true}, "alice@example.com"},
+		{"相似域名", map[string]any{"email": "alice@notexample.com"}, ""},
+		{"子域名", map[string]any{"email": "alice@mail.example.com"}, ""},
+		{"用户组", map[string]any{"email": "ops@other.com", "groups": []any{"dev", "admins"}}, "ops@other.com"},
+		{"不在任何列表", map[string]any{"email": "eve@other.com", "groups": []any{"dev"}}, ""},
+		{"邮箱未验证", map[string]any{"email": "alice@example.com", "email_verified": false}, ""},
+		{"缺少邮箱", map[string]any{"groups": []any{"admins"}}, ""},
+	}
+	for _, c := range cases {
+		got, err := o.authorize(c.claims)
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("%s: 应拒绝，实际通过为 %s", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: 期望 %s，实际 %s (%v)", c.name, c.want, got, err)
+		}
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	m := newMockIssuer(t)
+	ks := newKeySet(m.URL+"/jwks", http.DefaultClient)
+	forger := newTestSigner(t, "k1") // 与真实密钥同 kid，但私钥不同
+
+	base := m.validClaims("alice@example.com")
+	with := func(k string, v any) map[string]any {
+		c := make(map[string]any)
+		for key, val := range base {
+			c[key] = val
+		}
+		c[k] = v
+		return c
+	}
+	cases := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"有效", m.signer.sign(t, base), true},
+		{"aud 为数组", m.signer.sign(t, with("aud", []string{"other", testClientID})), true},
+		{"签名无效", forger.sign(t, base), false},
+		{"aud 不匹配", m.signer.sign(t, with("aud", "other-client")), false},
+		{"iss 不匹配", m.signer.sign(t, with("iss", "https://evil.example.com")), false},
+		{"已过期", m.signer.sign(t, with("exp", time.Now().Add(-2*jwtLeeway).Unix())), false},
+		{"缺少 exp", m.signer.sign(t, with("exp", nil)), false},
+		{"尚未生效", m.signer.sign(t, with("nbf", time.Now().Add(2*jwtLeeway).Unix())), false},
+		{"格式错误", "not-a-jwt", false},
+	}
+	for _, c := range cases {
+		_, err := verifyJWT(t.Context(), ks, c.token, m.URL, testClientID)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: 期望通过=%v，实际错误 %v", c.name, c.ok, err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package authproxy
+
+import (
+	"path"
+	"strings"
+)
+
+// matchPath 判断请求路径是否匹配 glob 模式，以 /* 结尾的模式匹配该前缀下任意层级
+func matchPath(pattern, p string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+	ok, _ := path.Match(pattern, p)
+	return ok
+}
+
+// matchAnyPath 判断请求路径是否匹配任一模式
+func matchAnyPath(patterns []string, p string) bool {
+	p = path.Clean("/" + p)
+	for _, pattern := range patterns {
+		if matchPath(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
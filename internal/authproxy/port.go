@@ -6,6 +6,19 @@ import (
 	"strconv"
 )
 
+// DefaultProxyPort 为路由分配代理端口或无法从上游推断端口时，从该端口开始探测
+const DefaultProxyPort = 18000
+
+// PortAvailable 判断本机端口当前是否可以监听
+func PortAvailable(port int) bool {
+	ln, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
 // FindAvailableListener 从 startPort 开始探测，返回第一个可用的 listener
 // 直接返回 listener 而非端口号，避免 TOCTOU 竞态
 func FindAvailableListener(startPort int) (net.Listener, error) {
@@ -5,23 +5,30 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
-	_ "embed"
 	"encoding/hex"
 	"fmt"
+	"html/template"
+	"log"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-//go:embed login.html
-var loginHTML []byte
-
 const cookieName = "__cftunnel_auth"
+const mfaCookieName = "__cftunnel_mfa"
+const authPathPrefix = "/___auth/"
 const loginPath = "/___auth/login"
+const totpPath = "/___auth/totp"
+const logoutPath = "/___auth/logout"
+
+// mfaTTL 密码验证通过后输入动态码的有效期
+const mfaTTL = 5 * time.Minute
 
 // RandomKey 生成 32 字节随机签名密钥
 func RandomKey() []byte {
@@ -30,42 +37,164 @@ func RandomKey() []byte {
 	return key
 }
 
+// 登录模式
+const (
+	ModePassword = "password" // 用户名密码表单（默认）
+	ModeOIDC     = "oidc"     // OpenID Connect 授权码 + PKCE
+	ModeAccess   = "access"   // 由 Cloudflare Access 登录，代理校验其签发的 JWT
+	ModeNone     = "none"     // 不需要登录，仅作为 IP 访问控制网关
+)
+
 // Config 鉴权代理配置
 type Config struct {
-	Username   string
-	Password   string
-	TargetPort string
-	SigningKey  []byte
+	Mode       string
+	Users      []User
+	OIDC       *OIDCConfig
+	Access     *AccessConfig
+	Route      string // 路由名称，显示在登录页
+	Hostname   string // 路由域名，用于校验 WebSocket 的 Origin，为空时使用请求 Host
+	SigningKey []byte
 	CookieTTL  time.Duration
+	// RetiredKeys 轮换前的旧签名密钥，宽限期内签发的 Cookie 仍然有效
+	RetiredKeys []RetiredKey
+
+	// Upstream 上游服务地址：http://、https://、unix:<socket> 或 unix+tls:<socket>
+	Upstream    string
+	NoTLSVerify bool   // 不校验上游证书
+	CAFile      string // 额外信任的 CA 证书文件（PEM）
+	ServerName  string // 上游 TLS SNI 与证书校验使用的主机名，默认取上游地址
+	HostHeader  string // 改写发往上游的 Host 头，默认保留访问域名
+	HTTP2       bool   // 与 HTTPS 上游协商 HTTP/2，默认与 cloudflared 一致只使用 HTTP/1.1
+
+	// Branding 登录页定制，为 nil 时使用内置页面
+	Branding *LoginBranding
+
+	// IdentityHeaders 转发给上游的身份头，为 nil 时使用 DefaultIdentityHeaders
+	IdentityHeaders *IdentityHeaders
+
+	// ListenPort 固定监听端口，为 0 时从上游端口 +1 开始自动探测
+	ListenPort int
+
+	// PublicPaths 无需登录即可访问的路径（glob，/* 结尾匹配任意层级），如 Webhook 回调
+	PublicPaths []string
+	// PublicWebSocketPaths 无需登录即可建立 WebSocket 的路径（glob，/* 结尾匹配任意层级）
+	PublicWebSocketPaths []string
+
+	// SessionFile 会话持久化文件，为空时会话仅保存在内存中
+	SessionFile string
+
+	// AllowIPs/DenyIPs 客户端 IP 的 CIDR 规则，拒绝优先，在登录校验之前生效
+	AllowIPs []string
+	DenyIPs  []string
+
+	// APIKeys 机器客户端的静态密钥，通过 Authorization: Bearer 或 APIKeyHeader 指定的头传递
+	APIKeys      []APIKey
+	APIKeyHeader string
 }
 
 // Proxy 鉴权反向代理
 type Proxy struct {
 	cfg      Config
+	users    map[string]User
+	keyID    string // 当前签名密钥的 ID
+	mfaKey   []byte
+	oidcKey  []byte
+	stateKey []byte
+	oidc     *oidcProvider
+	access   *accessVerifier
+
+	loginTmpl *template.Template
+	page      loginPage // 登录页的路由信息与定制项
+
+	limiter  *loginLimiter
+	sessions *sessionStore
+	ipFilter *ipFilter
 	listener net.Listener
 	server   *http.Server
 	reverse  *httputil.ReverseProxy
+
+	totpMu   sync.Mutex
+	totpUsed map[string]int64 // 用户最近一次使用的动态码时间窗口，防止重放
 }
 
 // New 创建鉴权代理实例，自动探测可用端口
 func New(cfg Config) (*Proxy, error) {
-	port, _ := strconv.Atoi(cfg.TargetPort)
-	ln, err := FindAvailableListener(port + 1)
+	if cfg.Mode == "" {
+		cfg.Mode = ModePassword
+	}
+	switch cfg.Mode {
+	case ModePassword, ModeNone:
+	case ModeOIDC:
+		if cfg.OIDC == nil || cfg.OIDC.Issuer == "" || cfg.OIDC.ClientID == "" {
+			return nil, fmt.Errorf("OIDC 模式需要配置 issuer 和 client_id")
+		}
+	case ModeAccess:
+		if cfg.Access == nil || cfg.Access.TeamDomain == "" || cfg.Access.Audience == "" {
+			return nil, fmt.Errorf("Access 模式需要配置团队域名和 AUD 标签")
+		}
+	default:
+		return nil, fmt.Errorf("未知的鉴权模式: %s", cfg.Mode)
+	}
+	filter, err := newIPFilter(cfg.AllowIPs, cfg.DenyIPs)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, page, err := newLoginPage(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	target, _ := url.Parse("http://127.0.0.1:" + cfg.TargetPort)
-	rp := httputil.NewSingleHostReverseProxy(target)
+	rp, port, err := newReverseProxy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var ln net.Listener
+	if cfg.ListenPort > 0 {
+		ln, err = net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(cfg.ListenPort))
+		if err != nil {
+			return nil, fmt.Errorf("端口 %d 已被占用: %w", cfg.ListenPort, err)
+		}
+	} else {
+		start := port + 1
+		if port == 0 {
+			start = DefaultProxyPort
+		}
+		if ln, err = FindAvailableListener(start); err != nil {
+			return nil, err
+		}
+	}
 
 	if cfg.CookieTTL == 0 {
 		cfg.CookieTTL = 24 * time.Hour
 	}
 
+	users := make(map[string]User, len(cfg.Users))
+	for _, u := range cfg.Users {
+		users[u.Username] = u
+	}
+
 	p := &Proxy{
 		cfg:      cfg,
+		users:    users,
+		keyID:    KeyID(cfg.SigningKey),
+		mfaKey:   deriveKey(cfg.SigningKey, "mfa"),
+		oidcKey:  deriveKey(cfg.SigningKey, "oidc"),
+		stateKey: deriveKey(cfg.SigningKey, "state"),
+		limiter:  newLoginLimiter(),
+		sessions: newSessionStore(cfg.SessionFile),
+		ipFilter: filter,
 		listener: ln,
 		reverse:  rp,
+		totpUsed: make(map[string]int64),
+
+		loginTmpl: tmpl,
+		page:      page,
+	}
+	switch cfg.Mode {
+	case ModeOIDC:
+		p.oidc = newOIDCProvider(*cfg.OIDC)
+	case ModeAccess:
+		p.access = newAccessVerifier(*cfg.Access)
 	}
 	p.server = &http.Server{Handler: p}
 	return p, nil
@@ -91,9 +220,43 @@ func (p *Proxy) Stop() error {
 
 // ServeHTTP 核心路由逻辑
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// WebSocket 升级请求直接透传
+	if !p.ipFilter.permit(ClientIP(r)) {
+		http.Error(w, "禁止访问", http.StatusForbidden)
+		return
+	}
+	if p.cfg.Mode == ModeNone || p.isPublic(r) {
+		p.forward(w, r, nil)
+		return
+	}
+	if p.cfg.Mode == ModeAccess {
+		p.serveAccess(w, r)
+		return
+	}
+
 	if isWebSocket(r) {
-		p.reverse.ServeHTTP(w, r)
+		p.serveWebSocket(w, r)
+		return
+	}
+
+	if r.URL.Path == logoutPath {
+		p.handleLogout(w, r)
+		return
+	}
+
+	// 机器客户端（Webhook、CI、CLI）携带 Basic 或 API Key 时不显示登录页
+	if !p.checkAuth(r) {
+		if id, attempted := p.machineAuth(r); attempted {
+			if id == nil {
+				unauthorized(w, r)
+				return
+			}
+			p.forward(w, r, id)
+			return
+		}
+	}
+
+	if p.cfg.Mode == ModeOIDC {
+		p.serveOIDC(w, r)
 		return
 	}
 
@@ -103,33 +266,224 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 动态码提交
+	if r.Method == http.MethodPost && r.URL.Path == totpPath {
+		p.handleTOTP(w, r)
+		return
+	}
+
 	// 检查 Cookie 鉴权
-	if p.checkAuth(r) {
-		p.reverse.ServeHTTP(w, r)
+	if sess := p.session(r); sess != nil {
+		if r.URL.Path == loginPath {
+			http.Redirect(w, r, p.returnTo(r.URL.Query().Get("state")), http.StatusSeeOther)
+			return
+		}
+		p.forward(w, r, p.sessionIdentity(sess))
+		return
+	}
+
+	// 未认证，返回登录页；登录后跳回当前地址
+	if r.URL.Path == loginPath {
+		p.renderLogin(w, r, http.StatusOK, loginPageFromQuery(r))
+		return
+	}
+	p.renderLogin(w, r, http.StatusOK, loginPage{State: p.signState(requestReturnPath(r))})
+}
+
+// isPublic 请求路径是否在公开路径中，代理自身的 /___auth/ 路径始终需要经过鉴权逻辑
+func (p *Proxy) isPublic(r *http.Request) bool {
+	if strings.HasPrefix(path.Clean("/"+r.URL.Path), authPathPrefix) {
+		return false
+	}
+	return matchAnyPath(p.cfg.PublicPaths, r.URL.Path)
+}
+
+// serveWebSocket WebSocket 升级请求需携带有效 Cookie 且 Origin 与路由域名一致
+func (p *Proxy) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if matchAnyPath(p.cfg.PublicWebSocketPaths, r.URL.Path) {
+		p.forward(w, r, nil)
+		return
+	}
+	if !p.sameOrigin(r) {
+		http.Error(w, "Origin 不允许", http.StatusForbidden)
+		return
+	}
+	if sess := p.session(r); sess != nil {
+		p.forward(w, r, p.sessionIdentity(sess))
+		return
+	}
+	id, _ := p.machineAuth(r)
+	if id == nil {
+		unauthorized(w, r)
+		return
+	}
+	p.forward(w, r, id)
+}
+
+// sameOrigin 校验 Origin 头（浏览器必定携带），防止跨站 WebSocket 劫持
+func (p *Proxy) sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := p.cfg.Hostname
+	if host == "" {
+		host = r.Host
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// serveOIDC OIDC 模式下的路由逻辑：未认证的页面请求跳转到身份提供方
+func (p *Proxy) serveOIDC(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == callbackPath {
+		p.handleCallback(w, r)
 		return
 	}
+	if sess := p.session(r); sess != nil {
+		p.forward(w, r, p.sessionIdentity(sess))
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "未登录", http.StatusUnauthorized)
+		return
+	}
+	p.startOIDC(w, r)
+}
 
-	// 未认证，返回登录页
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write(loginHTML)
+// serveAccess 登录由 Cloudflare Access 完成，代理只校验 JWT 并转发已验证的邮箱
+func (p *Proxy) serveAccess(w http.ResponseWriter, r *http.Request) {
+	email, err := p.access.verify(r.Context(), r)
+	if err != nil {
+		log.Printf("[authproxy] Access 校验失败 (%s): %v", ClientIP(r), err)
+		http.Error(w, "未通过 Cloudflare Access 验证", http.StatusForbidden)
+		return
+	}
+	p.forward(w, r, &identity{User: email, Email: email})
 }
 
 // handleLogin 处理登录表单提交
 func (p *Proxy) handleLogin(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
-	password := r.FormValue("password")
+	state := r.PostFormValue("state")
+	// 跨站提交或页面过期：直接以 403 重新显示登录页，不处理凭据
+	if !checkCSRF(r) {
+		p.renderLogin(w, r, http.StatusForbidden, loginPage{State: state, Error: csrfError})
+		return
+	}
+	username := r.PostFormValue("username")
+	password := r.PostFormValue("password")
+
+	ipKey, userKey := "ip="+ClientIP(r), "user="+username
+	if locked, _ := p.limiter.locked(ipKey, userKey); locked {
+		http.Redirect(w, r, loginURL(state, "error", "locked"), http.StatusSeeOther)
+		return
+	}
+
+	// 用户不存在时也校验一次哈希，避免通过响应时间枚举用户名
+	user, ok := p.users[username]
+	hash := user.PasswordHash
+	if !ok {
+		hash = dummyHash()
+	}
+	if !VerifyPassword(hash, password) || !ok {
+		p.limiter.fail(ipKey, userKey)
+		http.Redirect(w, r, loginURL(state, "error", "1"), http.StatusSeeOther)
+		return
+	}
+
+	// 已启用两步验证：签发短期 MFA Cookie，跳转到动态码输入页
+	if user.TOTPSecret != "" {
+		expiry := time.Now().Add(mfaTTL).Unix()
+		payload := fmt.Sprintf("%s:%x", username, expiry)
+		http.SetCookie(w, &http.Cookie{
+			Name:     mfaCookieName,
+			Value:    payload + "." + signPayload(p.mfaKey, payload),
+			Path:     "/",
+			MaxAge:   int(mfaTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, loginURL(state, "mfa", "1"), http.StatusSeeOther)
+		return
+	}
+
+	p.limiter.reset(ipKey, userKey)
+	p.issueCookie(w, r, username, nil)
+	http.Redirect(w, r, p.returnTo(state), http.StatusSeeOther)
+}
+
+// handleTOTP 校验两步验证动态码
+func (p *Proxy) handleTOTP(w http.ResponseWriter, r *http.Request) {
+	state := r.PostFormValue("state")
+	if !checkCSRF(r) {
+		p.renderLogin(w, r, http.StatusForbidden, loginPage{MFA: true, State: state, Error: csrfError})
+		return
+	}
+	cookie, err := r.Cookie(mfaCookieName)
+	if err != nil {
+		http.Redirect(w, r, loginURL(state), http.StatusSeeOther)
+		return
+	}
+	username, ok := verifyToken(p.mfaKey, cookie.Value)
+	user, exists := p.users[username]
+	if !ok || !exists || user.TOTPSecret == "" {
+		http.Redirect(w, r, loginURL(state), http.StatusSeeOther)
+		return
+	}
+
+	ipKey, userKey := "ip="+ClientIP(r), "user="+username
+	if locked, _ := p.limiter.locked(ipKey, userKey); locked {
+		http.Redirect(w, r, loginURL(state, "mfa", "1", "error", "locked"), http.StatusSeeOther)
+		return
+	}
 
-	if username != p.cfg.Username || password != p.cfg.Password {
-		http.Redirect(w, r, "/?error=1", http.StatusSeeOther)
+	counter, ok := ValidateTOTP(user.TOTPSecret, r.PostFormValue("code"), time.Now())
+	if ok {
+		p.totpMu.Lock()
+		if counter <= p.totpUsed[username] {
+			ok = false
+		} else {
+			p.totpUsed[username] = counter
+		}
+		p.totpMu.Unlock()
+	}
+	if !ok {
+		p.limiter.fail(ipKey, userKey)
+		http.Redirect(w, r, loginURL(state, "mfa", "1", "error", "1"), http.StatusSeeOther)
 		return
 	}
 
-	// 签发 Cookie
-	expiry := time.Now().Add(p.cfg.CookieTTL).Unix()
-	payload := fmt.Sprintf("%s:%x", username, expiry)
+	p.limiter.reset(ipKey, userKey)
+	http.SetCookie(w, &http.Cookie{Name: mfaCookieName, Value: "", Path: "/", MaxAge: -1})
+	p.issueCookie(w, r, username, nil)
+	http.Redirect(w, r, p.returnTo(state), http.StatusSeeOther)
+}
+
+// issueCookie 创建服务端会话并签发鉴权 Cookie
+func (p *Proxy) issueCookie(w http.ResponseWriter, r *http.Request, username string, groups []string) {
+	token := randomToken()
+	now := time.Now()
+	sess := &Session{
+		ID:          sessionID(token),
+		Username:    username,
+		Groups:      groups,
+		ClientIP:    ClientIP(r),
+		UserAgent:   r.UserAgent(),
+		Created:     now,
+		Expires:     now.Add(p.cfg.CookieTTL),
+		Fingerprint: p.fingerprint(p.cfg.SigningKey, username),
+	}
+	if err := p.sessions.add(sess); err != nil {
+		log.Printf("[authproxy] 保存会话失败: %v", err)
+	}
+
+	payload := fmt.Sprintf("%s:%s:%x", token, username, sess.Expires.Unix())
 	sig := signPayload(p.cfg.SigningKey, payload)
-	value := payload + "." + sig
+	value := p.keyID + "." + payload + "." + sig
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     cookieName,
@@ -140,40 +494,108 @@ func (p *Proxy) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Secure:   true,
 		SameSite: http.SameSiteLaxMode,
 	})
+}
+
+// handleLogout 注销当前会话并清除 Cookie；GET 只显示确认页，注销须以带 CSRF 令牌的 POST 提交
+func (p *Proxy) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		p.renderLogout(w, r, http.StatusOK)
+		return
+	}
+	if !checkCSRF(r) {
+		p.renderLogout(w, r, http.StatusForbidden)
+		return
+	}
+	if sess := p.session(r); sess != nil {
+		if err := p.sessions.remove(sess.ID); err != nil {
+			log.Printf("[authproxy] 删除会话失败: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Value: "", Path: "/", MaxAge: -1})
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 // checkAuth 校验请求中的鉴权 Cookie
 func (p *Proxy) checkAuth(r *http.Request) bool {
+	return p.session(r) != nil
+}
+
+// session 校验 Cookie 签名并返回对应的有效会话
+// Cookie 格式：key_id.token:username:expiry_hex.hmac_hex
+func (p *Proxy) session(r *http.Request) *Session {
 	cookie, err := r.Cookie(cookieName)
 	if err != nil {
-		return false
+		return nil
 	}
+	kid, signed, _ := strings.Cut(cookie.Value, ".")
+	key, ok := p.verifyKey(kid)
+	if !ok {
+		return nil
+	}
+	value, ok := verifyToken(key, signed)
+	if !ok {
+		return nil
+	}
+	token, username, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil
+	}
+	sess := p.sessions.get(sessionID(token))
+	if sess == nil || sess.Username != username {
+		return nil
+	}
+	if !hmac.Equal([]byte(sess.Fingerprint), []byte(p.fingerprint(key, username))) {
+		return nil
+	}
+	return sess
+}
 
-	// 格式：username:expiry_hex.hmac_hex
-	dotIdx := strings.LastIndex(cookie.Value, ".")
+// fingerprint 用签发会话的密钥计算用户当前凭据的指纹，用户密码、两步验证变更或签名密钥失效后其会话全部失效
+func (p *Proxy) fingerprint(key []byte, username string) string {
+	if p.cfg.Mode == ModeOIDC {
+		return signPayload(key, "oidc:"+p.oidc.cfg.Issuer+":"+p.oidc.cfg.ClientID)
+	}
+	u, ok := p.users[username]
+	if !ok {
+		return ""
+	}
+	return signPayload(key, "user:"+u.Username+":"+u.PasswordHash+":"+u.TOTPSecret)
+}
+
+// verifyToken 校验签名令牌并返回过期时间之前的内容
+// 格式：payload:expiry_hex.hmac_hex
+func verifyToken(key []byte, value string) (string, bool) {
+	dotIdx := strings.LastIndex(value, ".")
 	if dotIdx < 0 {
-		return false
+		return "", false
 	}
-	payload := cookie.Value[:dotIdx]
-	sig := cookie.Value[dotIdx+1:]
+	payload := value[:dotIdx]
+	sig := value[dotIdx+1:]
 
 	// 验证签名
-	if signPayload(p.cfg.SigningKey, payload) != sig {
-		return false
+	if !hmac.Equal([]byte(signPayload(key, payload)), []byte(sig)) {
+		return "", false
 	}
 
 	// 验证过期时间
 	colonIdx := strings.LastIndex(payload, ":")
 	if colonIdx < 0 {
-		return false
+		return "", false
 	}
 	expiryHex := payload[colonIdx+1:]
 	expiry, err := strconv.ParseInt(expiryHex, 16, 64)
 	if err != nil {
-		return false
+		return "", false
+	}
+	if time.Now().Unix() >= expiry {
+		return "", false
 	}
-	return time.Now().Unix() < expiry
+	return payload[:colonIdx], true
+}
+
+// deriveKey 从签名密钥派生出用于特定用途的子密钥，避免不同 Cookie 互相冒用
+func deriveKey(key []byte, purpose string) []byte {
+	return []byte(signPayload(key, purpose))
 }
 
 // signPayload 使用 HMAC-SHA256 签名
@@ -0,0 +1,459 @@
+package authproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCSRF 测试用的双重提交令牌，与 csrfCookie 配合使用
+var (
+	testCSRF   = strings.Repeat("c", 32)
+	csrfCookie = &http.Cookie{Name: csrfCookieName, Value: testCSRF}
+)
+
+// newEchoUpstream 返回把收到的请求头与 Cookie 以文本形式回显的上游
+func newEchoUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "path=%s\n", r.URL.Path)
+		for k, v := range r.Header {
+			fmt.Fprintf(w, "%s=%s\n", k, strings.Join(v, ","))
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// newTestProxy 构建指向回显上游的代理，未指定签名密钥时随机生成
+func newTestProxy(t *testing.T, cfg Config) *Proxy {
+	t.Helper()
+	if cfg.SigningKey == nil {
+		cfg.SigningKey = RandomKey()
+	}
+	if cfg.Upstream == "" {
+		cfg.Upstream = newEchoUpstream(t).URL
+	}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.listener.Close() })
+	return p
+}
+
+func testUser(t *testing.T, username, password string) User {
+	t.Helper()
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return User{Username: username, PasswordHash: hash}
+}
+
+func get(p *Proxy, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	return serve(p, httptest.NewRequest(http.MethodGet, "https://app.example.com"+path, nil), cookies...)
+}
+
+func postForm(p *Proxy, path string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "https://app.example.com"+path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return serve(p, r, cookies...)
+}
+
+// login 提交登录表单，返回响应
+func login(p *Proxy, username, password string) *httptest.ResponseRecorder {
+	return postForm(p, loginPath, url.Values{"username": {username}, "password": {password}, "csrf": {testCSRF}}, csrfCookie)
+}
+
+func TestLogoutRequiresPostWithCSRF(t *testing.T) {
+	p := newTestProxy(t, Config{Users: []User{testUser(t, "alice", "secret")}})
+
+	cleared := func(w *httptest.ResponseRecorder) bool {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == cookieName && c.MaxAge < 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	w := get(p, logoutPath)
+	if w.Code != http.StatusOK || cleared(w) {
+		t.Fatalf("GET 只应显示确认页: %d", w.Code)
+	}
+	csrf := findCookie(w, csrfCookieName)
+	if csrf == nil || !strings.Contains(w.Body.String(), csrf.Value) {
+		t.Fatal("确认页应携带 CSRF 令牌")
+	}
+
+	if w := postForm(p, logoutPath, url.Values{"csrf": {"forged"}}, csrf); cleared(w) || w.Code != http.StatusForbidden {
+		t.Fatalf("CSRF 令牌不匹配时不应注销: %d", w.Code)
+	}
+	if w := postForm(p, logoutPath, url.Values{"csrf": {csrf.Value}}); cleared(w) {
+		t.Fatal("缺少 CSRF Cookie 时不应注销")
+	}
+	if w := postForm(p, logoutPath, url.Values{"csrf": {csrf.Value}}, csrf); !cleared(w) || w.Code != http.StatusSeeOther {
+		t.Fatalf("携带有效令牌的 POST 应注销: %d", w.Code)
+	}
+}
+
+func TestUpstreamHTTP2(t *testing.T) {
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.StartTLS()
+	t.Cleanup(upstream.Close)
+
+	for _, c := range []struct {
+		http2 bool
+		want  string
+	}{{false, "HTTP/1.1"}, {true, "HTTP/2.0"}} {
+		p, err := New(Config{Mode: ModeNone, Upstream: upstream.URL, NoTLSVerify: true, HTTP2: c.http2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := serve(p, httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil))
+		p.listener.Close()
+		if w.Body.String() != c.want {
+			t.Errorf("HTTP2=%v: 上游收到 %q，期望 %s", c.http2, w.Body.String(), c.want)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	p := newTestProxy(t, Config{Users: []User{testUser(t, "alice", "secret"), testUser(t, "bob", "hunter2")}})
+	locked := func(w *httptest.ResponseRecorder) bool {
+		return strings.Contains(w.Header().Get("Location"), "error=locked")
+	}
+
+	for i := 0; i <= limiterFreeAttempts; i++ {
+		if w := login(p, "alice", "wrong"); locked(w) || findCookie(w, cookieName) != nil {
+			t.Fatalf("第 %d 次失败前不应锁定", i+1)
+		}
+	}
+	// 锁定期间正确的密码也被拒绝，同一 IP 的其他用户同样受限
+	if w := login(p, "alice", "secret"); !locked(w) || findCookie(w, cookieName) != nil {
+		t.Fatal("连续失败后应锁定账户")
+	}
+	if w := login(p, "bob", "hunter2"); !locked(w) {
+		t.Fatal("连续失败后应锁定来源 IP")
+	}
+
+	// 锁定到期后恢复
+	p.limiter.mu.Lock()
+	for _, a := range p.limiter.attempts {
+		a.lockedUntil = time.Now().Add(-time.Second)
+	}
+	p.limiter.mu.Unlock()
+	if w := login(p, "alice", "secret"); locked(w) || findCookie(w, cookieName) == nil {
+		t.Fatal("锁定到期后应允许登录")
+	}
+	// 登录成功清除失败记录，再失败一次不会立即锁定
+	if w := login(p, "alice", "wrong"); locked(w) {
+		t.Fatal("登录成功后失败计数应清零")
+	}
+}
+
+func TestMachineAuth(t *testing.T) {
+	key, hash := GenerateAPIKey()
+	revoked, _ := GenerateAPIKey()
+	mfaUser := testUser(t, "carol", "secret")
+	mfaUser.TOTPSecret = rfc6238Secret
+	p := newTestProxy(t, Config{
+		Users:        []User{testUser(t, "alice", "secret"), mfaUser},
+		APIKeys:      []APIKey{{Name: "ci", Hash: hash}},
+		APIKeyHeader: "X-Api-Key",
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		code   int // 0 表示不应转发，但交由登录页处理
+	}{
+		{"有效 API Key", "Authorization", "Bearer " + key, http.StatusOK},
+		{"自定义头中的 API Key", "X-Api-Key", key, http.StatusOK},
+		{"已撤销的 API Key", "Authorization", "Bearer " + revoked, http.StatusUnauthorized},
+		{"前缀错误的 Bearer 令牌", "Authorization", "Bearer " + strings.TrimPrefix(key, apiKeyPrefix), 0},
+		{"自定义头中前缀错误的密钥", "X-Api-Key", "xyz_" + strings.TrimPrefix(key, apiKeyPrefix), http.StatusUnauthorized},
+		{"Basic 密码正确", "Authorization", basicAuth("alice", "secret"), http.StatusOK},
+		{"Basic 密码错误", "Authorization", basicAuth("alice", "wrong"), http.StatusUnauthorized},
+		{"Basic 启用两步验证的用户", "Authorization", basicAuth("carol", "secret"), http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "https://app.example.com/api", nil)
+		r.Header.Set(c.header, c.value)
+		w := serve(p, r)
+		forwarded := strings.Contains(w.Body.String(), "path=/api")
+		switch {
+		case c.code == http.StatusOK && (!forwarded || strings.Contains(w.Body.String(), c.value)):
+			t.Errorf("%s: 应转发且移除凭据: %d %q", c.name, w.Code, w.Body.String())
+		case c.code != http.StatusOK && forwarded:
+			t.Errorf("%s: 不应转发到上游", c.name)
+		case c.code != 0 && w.Code != c.code:
+			t.Errorf("%s: 期望状态码 %d，实际 %d", c.name, c.code, w.Code)
+		}
+	}
+}
+
+func basicAuth(username, password string) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(username, password)
+	return r.Header.Get("Authorization")
+}
+
+func TestLoginRequiresCSRF(t *testing.T) {
+	user := testUser(t, "alice", "secret")
+	p := newTestProxy(t, Config{Users: []User{user}})
+	creds := url.Values{"username": {"alice"}, "password": {"secret"}}
+
+	// 跨站页面无法读取 CSRF Cookie，只能提交缺少或伪造的令牌
+	forged := url.Values{"username": {"alice"}, "password": {"secret"}, "csrf": {"forged"}}
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"缺少令牌":      postForm(p, loginPath, creds, csrfCookie),
+		"缺少 Cookie": postForm(p, loginPath, url.Values{"username": {"alice"}, "password": {"secret"}, "csrf": {testCSRF}}),
+		"令牌不匹配":     postForm(p, loginPath, forged, csrfCookie),
+		"动态码缺少令牌":   postForm(p, totpPath, url.Values{"code": {"123456"}}, csrfCookie),
+	} {
+		if w.Code != http.StatusForbidden || findCookie(w, cookieName) != nil {
+			t.Errorf("%s: 应返回 403 且不签发 Cookie，实际 %d", name, w.Code)
+		}
+	}
+	if w := login(p, "alice", "secret"); findCookie(w, cookieName) == nil {
+		t.Fatal("携带有效令牌应登录成功")
+	}
+}
+
+func TestLoginIgnoresExternalReturnTo(t *testing.T) {
+	p := newTestProxy(t, Config{Users: []User{testUser(t, "alice", "secret")}})
+	loginWithState := func(state string) string {
+		form := url.Values{"username": {"alice"}, "password": {"secret"}, "csrf": {testCSRF}, "state": {state}}
+		return postForm(p, loginPath, form, csrfCookie).Header().Get("Location")
+	}
+
+	cases := []struct {
+		name  string
+		state string
+		want  string
+	}{
+		{"站内路径", p.signState("/dashboard?tab=1"), "/dashboard?tab=1"},
+		{"未签名的外部地址", "https://evil.com", "/"},
+		{"未签名的站内路径", "/dashboard", "/"},
+		{"协议相对地址", p.signState("//evil.com"), "/"},
+		{"反斜杠地址", p.signState("/\\evil.com"), "/"},
+		{"绝对地址", p.signState("https://evil.com"), "/"},
+		{"鉴权路径", p.signState(logoutPath), "/"},
+		{"篡改签名", p.signState("/dashboard") + "x", "/"},
+	}
+	for _, c := range cases {
+		if got := loginWithState(c.state); got != c.want {
+			t.Errorf("%s: 登录后跳转到 %q，期望 %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	key, hash := GenerateAPIKey()
+	p := newTestProxy(t, Config{
+		Users:                []User{testUser(t, "alice", "secret")},
+		Hostname:             "app.example.com",
+		APIKeys:              []APIKey{{Name: "ci", Hash: hash}},
+		PublicWebSocketPaths: []string{"/public/*"},
+	})
+	auth := findCookie(login(p, "alice", "secret"), cookieName)
+	if auth == nil {
+		t.Fatal("登录失败")
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		origin string
+		cookie *http.Cookie
+		bearer string
+		code   int
+	}{
+		{"有效会话", "/ws", "https://app.example.com", auth, "", http.StatusOK},
+		{"有效会话且无 Origin", "/ws", "", auth, "", http.StatusOK},
+		{"未登录", "/ws", "https://app.example.com", nil, "", http.StatusUnauthorized},
+		{"跨站 Origin", "/ws", "https://evil.com", auth, "", http.StatusForbidden},
+		{"伪装子域名的 Origin", "/ws", "https://app.example.com.evil.com", auth, "", http.StatusForbidden},
+		{"伪造 Cookie", "/ws", "", &http.Cookie{Name: cookieName, Value: "forged"}, "", http.StatusUnauthorized},
+		{"API Key", "/ws", "", nil, key, http.StatusOK},
+		{"无效 API Key", "/ws", "", nil, apiKeyPrefix + "forged", http.StatusUnauthorized},
+		{"公开 WebSocket 路径", "/public/feed", "https://evil.com", nil, "", http.StatusOK},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "https://app.example.com"+c.path, nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if c.bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+c.bearer)
+		}
+		w := serve(p, r, c.cookie)
+		if w.Code != c.code {
+			t.Errorf("%s: 期望状态码 %d，实际 %d", c.name, c.code, w.Code)
+		}
+		if forwarded := strings.Contains(w.Body.String(), "path="+c.path); forwarded != (c.code == http.StatusOK) {
+			t.Errorf("%s: 是否转发到上游与预期不符", c.name)
+		}
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	p := newTestProxy(t, Config{
+		Mode:     ModeNone,
+		AllowIPs: []string{"203.0.113.0/24", "2001:db8::/32"},
+		DenyIPs:  []string{"203.0.113.66"},
+	})
+
+	cases := []struct {
+		name   string
+		remote string
+		cfIP   string
+		code   int
+	}{
+		{"允许网段内", "203.0.113.5:1234", "", http.StatusOK},
+		{"IPv6 允许网段内", "[2001:db8::1]:1234", "", http.StatusOK},
+		{"拒绝优先于允许", "203.0.113.66:1234", "", http.StatusForbidden},
+		{"不在允许网段", "198.51.100.7:1234", "", http.StatusForbidden},
+		{"本机 cloudflared 转发的访客 IP", "127.0.0.1:1234", "203.0.113.5", http.StatusOK},
+		{"本机转发被拒绝的访客 IP", "127.0.0.1:1234", "203.0.113.66", http.StatusForbidden},
+		{"非本机连接伪造 CF-Connecting-IP", "198.51.100.7:1234", "203.0.113.5", http.StatusForbidden},
+		{"本机连接但访客 IP 无效", "127.0.0.1:1234", "not-an-ip", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+		r.RemoteAddr = c.remote
+		if c.cfIP != "" {
+			r.Header.Set("CF-Connecting-IP", c.cfIP)
+		}
+		if w := serve(p, r); w.Code != c.code {
+			t.Errorf("%s: 期望状态码 %d，实际 %d", c.name, c.code, w.Code)
+		}
+	}
+}
+
+func TestPublicPaths(t *testing.T) {
+	p := newTestProxy(t, Config{
+		Users:       []User{testUser(t, "alice", "secret")},
+		PublicPaths: []string{"/webhook/*", "/health", "/static/*.css"},
+		DenyIPs:     []string{"198.51.100.0/24"},
+	})
+
+	cases := []struct {
+		name   string
+		target string
+		public bool
+	}{
+		{"前缀本身", "/webhook", true},
+		{"前缀下任意层级", "/webhook/github/push", true},
+		{"精确路径", "/health", true},
+		{"单层通配", "/static/app.css", true},
+		{"单层通配不跨目录", "/static/css/app.css", false},
+		{"相似前缀", "/webhooks", false},
+		{"精确路径的子路径", "/health/db", false},
+		{"路径穿越", "/webhook/../admin", false},
+		{"编码的路径穿越", "/webhook/%2e%2e/admin", false},
+		{"鉴权路径", "/webhook/../___auth/logout", false},
+		{"未公开路径", "/admin", false},
+	}
+	for _, c := range cases {
+		w := get(p, c.target)
+		if forwarded := strings.Contains(w.Body.String(), "path="); forwarded != c.public {
+			t.Errorf("%s: %s 期望公开=%v，实际状态码 %d", c.name, c.target, c.public, w.Code)
+		}
+	}
+
+	// 公开路径同样受 IP 规则约束
+	r := httptest.NewRequest(http.MethodGet, "https://app.example.com/health", nil)
+	r.RemoteAddr = "198.51.100.7:1234"
+	if w := serve(p, r); w.Code != http.StatusForbidden {
+		t.Fatalf("被拒绝的 IP 不应访问公开路径: %d", w.Code)
+	}
+}
+
+func TestIdentityHeaders(t *testing.T) {
+	// 伪造默认身份头、Access 邮箱头以及路由自定义的身份头
+	spoof := func(r *http.Request, custom *IdentityHeaders) {
+		r.Header.Set("X-Forwarded-User", "admin")
+		r.Header.Set("X-Forwarded-Email", "admin@example.com")
+		r.Header.Set("X-Auth-Request-Groups", "admins")
+		r.Header.Set(accessEmailHeader, "admin@example.com")
+		if custom != nil && custom.User != "" {
+			r.Header.Set(custom.User, "admin")
+		}
+		r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+		r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: testCSRF})
+		r.AddCookie(&http.Cookie{Name: mfaCookieName, Value: "x"})
+	}
+	users := []User{testUser(t, "alice", "secret")}
+
+	cases := []struct {
+		name    string
+		cfg     Config
+		path    string
+		session bool
+		want    []string // 上游应收到的头，其余身份头均不应出现
+	}{
+		{"默认身份头", Config{Users: users}, "/", true,
+			[]string{"X-Forwarded-User=alice"}},
+		{"自定义身份头", Config{Users: users, IdentityHeaders: &IdentityHeaders{User: "X-User"}}, "/", true,
+			[]string{"X-User=alice"}},
+		{"关闭身份头", Config{Users: users, IdentityHeaders: &IdentityHeaders{}}, "/", true, nil},
+		{"公开路径", Config{Users: users, PublicPaths: []string{"/hook"}}, "/hook", false, nil},
+		{"无需登录模式", Config{Mode: ModeNone}, "/", false, nil},
+	}
+	identityHeaders := []string{"X-Forwarded-User=", "X-Forwarded-Email=", "X-Auth-Request-Groups=", "X-User=", accessEmailHeader + "="}
+	for _, c := range cases {
+		p := newTestProxy(t, c.cfg)
+		r := httptest.NewRequest(http.MethodGet, "https://app.example.com"+c.path, nil)
+		spoof(r, c.cfg.IdentityHeaders)
+		var auth *http.Cookie
+		if c.session {
+			if auth = findCookie(login(p, "alice", "secret"), cookieName); auth == nil {
+				t.Fatalf("%s: 登录失败", c.name)
+			}
+		}
+		body := serve(p, r, auth).Body.String()
+		if !strings.Contains(body, "path="+c.path) {
+			t.Errorf("%s: 请求未转发到上游", c.name)
+			continue
+		}
+		lines := strings.Split(body, "\n")
+		for _, line := range lines {
+			if strings.Contains(line, "admin") {
+				t.Errorf("%s: 客户端伪造的身份头到达上游: %s", c.name, line)
+			}
+			if strings.HasPrefix(line, "Cookie=") && (strings.Contains(line, "__cftunnel_") || !strings.Contains(line, "theme=dark")) {
+				t.Errorf("%s: 应只移除本代理的 Cookie: %s", c.name, line)
+			}
+		}
+		for _, prefix := range identityHeaders {
+			got := ""
+			for _, line := range lines {
+				if strings.HasPrefix(line, prefix) {
+					got = line
+				}
+			}
+			want := ""
+			for _, w := range c.want {
+				if strings.HasPrefix(w, prefix) {
+					want = w
+				}
+			}
+			if got != want {
+				t.Errorf("%s: 期望 %q，实际 %q", c.name, want, got)
+			}
+		}
+	}
+}
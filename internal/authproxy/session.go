@@ -0,0 +1,244 @@
+package authproxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sessionReloadInterval 检查会话文件是否被其他进程（如 cftunnel auth sessions）修改的最小间隔
+const sessionReloadInterval = time.Second
+
+// sessionLockTimeout 等待会话文件锁的最长时间，sessionLockStale 之前的锁文件视为进程崩溃遗留
+const (
+	sessionLockTimeout = 5 * time.Second
+	sessionLockStale   = 10 * time.Second
+)
+
+// Session 服务端会话，ID 为 Cookie 中随机令牌的 SHA-256，文件泄露也无法冒用
+type Session struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	Groups      []string  `json:"groups,omitempty"` // OIDC 登录时的用户组，转发给上游
+	ClientIP    string    `json:"client_ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+	Fingerprint string    `json:"fingerprint"` // 签发时的凭据指纹，密码或签名密钥变更后不再匹配
+}
+
+// sessionStore 会话存储，path 为空时仅保存在内存中
+type sessionStore struct {
+	path string
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newSessionStore(path string) *sessionStore {
+	s := &sessionStore{path: path, sessions: make(map[string]*Session)}
+	s.reload()
+	return s
+}
+
+// sessionID 由 Cookie 中的令牌计算会话 ID
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *sessionStore) get(id string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) > sessionReloadInterval {
+		s.reload()
+	}
+	sess, ok := s.sessions[id]
+	if !ok || time.Now().After(sess.Expires) {
+		return nil
+	}
+	return sess
+}
+
+func (s *sessionStore) add(sess *Session) error {
+	return s.update(func() { s.sessions[sess.ID] = sess })
+}
+
+func (s *sessionStore) remove(id string) error {
+	return s.update(func() { delete(s.sessions, id) })
+}
+
+// update 在文件锁内重新读取会话文件后再修改并写回，避免覆盖其他进程同时注销的会话
+func (s *sessionStore) update(fn func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path != "" {
+		unlock, err := lockSessions(s.path)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		s.modTime = time.Time{}
+	}
+	s.reload()
+	fn()
+	return s.save()
+}
+
+// reload 文件修改时间变化时重新读取（调用方需持有锁）
+func (s *sessionStore) reload() {
+	s.checkedAt = time.Now()
+	if s.path == "" {
+		return
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.sessions = make(map[string]*Session)
+			s.modTime = time.Time{}
+		}
+		return
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return
+	}
+	list, err := readSessions(s.path)
+	if err != nil {
+		return
+	}
+	s.sessions = make(map[string]*Session, len(list))
+	for i := range list {
+		s.sessions[list[i].ID] = &list[i]
+	}
+	s.modTime = info.ModTime()
+}
+
+// save 清理过期会话后写回文件（调用方需持有锁）
+func (s *sessionStore) save() error {
+	now := time.Now()
+	list := make([]Session, 0, len(s.sessions))
+	for id, sess := range s.sessions {
+		if now.After(sess.Expires) {
+			delete(s.sessions, id)
+			continue
+		}
+		list = append(list, *sess)
+	}
+	if s.path == "" {
+		return nil
+	}
+	if err := writeSessions(s.path, list); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func readSessions(path string) ([]Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []Session
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// writeSessions 先写临时文件再替换，避免其他进程读到半截内容
+func writeSessions(path string, list []Session) error {
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lockSessions 以独占创建锁文件的方式锁定会话文件，跨进程（代理与 CLI）互斥读改写
+func lockSessions(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	lock := path + ".lock"
+	deadline := time.Now().Add(sessionLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > sessionLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("会话文件 %s 被占用，请稍后重试", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// ListSessions 读取会话文件中未过期的会话
+func ListSessions(path string) ([]Session, error) {
+	list, err := readSessions(path)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := list[:0]
+	for _, s := range list {
+		if now.Before(s.Expires) {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSessions 删除满足条件的会话，返回删除数量；运行中的代理会在 1 秒内感知
+func RevokeSessions(path string, match func(Session) bool) (int, error) {
+	unlock, err := lockSessions(path)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	list, err := ListSessions(path)
+	if err != nil {
+		return 0, err
+	}
+	kept := make([]Session, 0, len(list))
+	for _, s := range list {
+		if !match(s) {
+			kept = append(kept, s)
+		}
+	}
+	revoked := len(list) - len(kept)
+	if revoked == 0 {
+		return 0, nil
+	}
+	return revoked, writeSessions(path, kept)
+}
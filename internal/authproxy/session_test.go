@@ -0,0 +1,103 @@
+package authproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreKeepsConcurrentRevocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "route.json")
+	expires := time.Now().Add(time.Hour)
+	store := newSessionStore(path)
+	if err := store.add(&Session{ID: "aaaaaaaa01", Username: "alice", Expires: expires}); err != nil {
+		t.Fatal(err)
+	}
+
+	// CLI 注销的同时代理写入新会话：写入前必须重新读取文件，不能用内存副本覆盖
+	n, err := RevokeSessions(path, func(s Session) bool { return s.ID == "aaaaaaaa01" })
+	if err != nil || n != 1 {
+		t.Fatalf("注销失败: %d %v", n, err)
+	}
+	store.modTime = time.Now() // 模拟修改时间精度不足，未察觉文件变化
+	if err := store.add(&Session{ID: "bbbbbbbb02", Username: "bob", Expires: expires}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ListSessions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != "bbbbbbbb02" {
+		t.Fatalf("已注销的会话被写回: %+v", list)
+	}
+	if store.get("aaaaaaaa01") != nil {
+		t.Fatal("代理内存中仍保留已注销的会话")
+	}
+}
+
+func TestSessionLockWaitsForHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "route.json")
+	unlock, err := lockSessions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := RevokeSessions(path, func(Session) bool { return true })
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("持有锁期间不应写入会话文件")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetiredKeyGrace(t *testing.T) {
+	sessions := filepath.Join(t.TempDir(), "route.json")
+	users := []User{testUser(t, "alice", "secret")}
+	oldKey, newKey := RandomKey(), RandomKey()
+
+	issuer := newTestProxy(t, Config{Users: users, SigningKey: oldKey, SessionFile: sessions})
+	auth := findCookie(login(issuer, "alice", "secret"), cookieName)
+	if auth == nil {
+		t.Fatal("登录失败")
+	}
+
+	// 轮换密钥后重启代理：旧 Cookie 在宽限期内有效，过期后失效
+	cases := []struct {
+		name    string
+		retired []RetiredKey
+		ok      bool
+	}{
+		{"宽限期内", []RetiredKey{{Key: oldKey, Expires: time.Now().Add(time.Hour)}}, true},
+		{"宽限期已过", []RetiredKey{{Key: oldKey, Expires: time.Now().Add(-time.Second)}}, false},
+		{"旧密钥已移除", nil, false},
+	}
+	for _, c := range cases {
+		p := newTestProxy(t, Config{Users: users, SigningKey: newKey, RetiredKeys: c.retired, SessionFile: sessions})
+		if ok := p.session(withCookie(auth)) != nil; ok != c.ok {
+			t.Errorf("%s: 期望会话有效=%v", c.name, c.ok)
+		}
+	}
+
+	// 不含密钥 ID 的 Cookie 一律拒绝，即使签名来自当前密钥
+	_, legacy, _ := strings.Cut(auth.Value, ".")
+	if issuer.session(withCookie(&http.Cookie{Name: cookieName, Value: legacy})) != nil {
+		t.Fatal("不含密钥 ID 的 Cookie 不应通过")
+	}
+}
+
+func withCookie(c *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+	r.AddCookie(c)
+	return r
+}
@@ -0,0 +1,86 @@
+package authproxy
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA-1 测试向量的密钥 "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	// RFC 给出 8 位动态码，6 位动态码取其后 6 位
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		counter, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok || counter != v.unix/totpPeriod {
+			t.Errorf("T=%d 动态码 %s 应通过，实际 ok=%v counter=%d", v.unix, v.code, ok, counter)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+	counter := now.Unix() / totpPeriod
+	cases := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"前一个窗口", hotp(key, counter-1), true},
+		{"后一个窗口", hotp(key, counter+1), true},
+		{"超出偏差", hotp(key, counter-2), false},
+		{"位数不足", "81804", false},
+		{"非数字", "abcdef", false},
+	}
+	for _, c := range cases {
+		if _, ok := ValidateTOTP(rfc6238Secret, c.code, now); ok != c.ok {
+			t.Errorf("%s: 期望通过=%v", c.name, c.ok)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now); ok {
+		t.Error("无效密钥不应通过")
+	}
+}
+
+func TestTOTPRejectsReplay(t *testing.T) {
+	user := testUser(t, "alice", "secret")
+	user.TOTPSecret = rfc6238Secret
+	p := newTestProxy(t, Config{Users: []User{user}})
+
+	key, _ := totpEncoding.DecodeString(rfc6238Secret)
+	code := hotp(key, time.Now().Unix()/totpPeriod)
+	submit := func() bool {
+		t.Helper()
+		w := login(p, "alice", "secret")
+		mfa := findCookie(w, mfaCookieName)
+		if mfa == nil || !strings.Contains(w.Header().Get("Location"), "mfa=1") {
+			t.Fatalf("密码正确后应要求动态码: %s", w.Header().Get("Location"))
+		}
+		if findCookie(w, cookieName) != nil {
+			t.Fatal("通过两步验证前不应签发鉴权 Cookie")
+		}
+		w = postForm(p, totpPath, url.Values{"code": {code}, "csrf": {testCSRF}}, csrfCookie, mfa)
+		return findCookie(w, cookieName) != nil
+	}
+
+	if !submit() {
+		t.Fatal("正确的动态码应登录成功")
+	}
+	if submit() {
+		t.Fatal("同一动态码不能重复使用")
+	}
+}
@@ -0,0 +1,98 @@
+package authproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// parseUpstream 解析上游地址，返回反向代理目标与 unix socket 路径（非 socket 时为空）
+func parseUpstream(raw string) (*url.URL, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("上游地址无效: %s", raw)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("上游地址缺少主机: %s", raw)
+		}
+		return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}, "", nil
+	case "unix", "unix+tls":
+		socket := u.Path
+		if socket == "" {
+			socket = u.Opaque
+		}
+		if socket == "" {
+			return nil, "", fmt.Errorf("上游地址缺少 socket 路径: %s", raw)
+		}
+		scheme := "http"
+		if u.Scheme == "unix+tls" {
+			scheme = "https"
+		}
+		return &url.URL{Scheme: scheme, Host: "localhost"}, socket, nil
+	default:
+		return nil, "", fmt.Errorf("鉴权代理不支持 %s 协议的上游: %s", u.Scheme, raw)
+	}
+}
+
+// newReverseProxy 按上游地址与 TLS 选项构建反向代理，同时返回上游端口（unix socket 或未指定时为 0）
+func newReverseProxy(cfg Config) (*httputil.ReverseProxy, int, error) {
+	target, socket, err := parseUpstream(cfg.Upstream)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.NoTLSVerify, ServerName: cfg.ServerName}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取 CA 证书失败: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, 0, fmt.Errorf("CA 证书 %s 中没有有效的 PEM 证书", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsCfg
+	// HTTP/2 仅通过 TLS ALPN 协商；WebSocket 升级请求仍会单独使用 HTTP/1.1 连接
+	tr.ForceAttemptHTTP2 = cfg.HTTP2
+	if !cfg.HTTP2 {
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if socket != "" {
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	rp := httputil.NewSingleHostReverseProxy(target)
+	rp.Transport = tr
+	if cfg.HostHeader != "" {
+		director := rp.Director
+		rp.Director = func(r *http.Request) {
+			director(r)
+			r.Host = cfg.HostHeader
+		}
+	}
+
+	port := 0
+	if socket == "" {
+		port, _ = strconv.Atoi(target.Port())
+	}
+	return rp, port, nil
+}
@@ -0,0 +1,131 @@
+package authproxy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User 鉴权用户，PasswordHash 支持 bcrypt、argon2id 以及 htpasswd 的 {SHA} 格式
+type User struct {
+	Username     string
+	PasswordHash string
+	TOTPSecret   string // 非空时登录需额外输入动态码
+}
+
+// HashPassword 使用 bcrypt 生成密码哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("生成密码哈希失败: %w", err)
+	}
+	return string(hash), nil
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     string
+)
+
+// dummyHash 返回一个随机密码的 bcrypt 哈希，用于用户不存在时消耗同等校验时间
+func dummyHash() string {
+	dummyOnce.Do(func() {
+		dummy, _ = HashPassword(randomToken())
+	})
+	return dummy
+}
+
+// SupportedHash 判断哈希格式是否可被 VerifyPassword 校验
+func SupportedHash(hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return true
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return true
+	case strings.HasPrefix(hash, "{SHA}"):
+		return true
+	}
+	return false
+}
+
+// VerifyPassword 校验明文密码是否与哈希匹配，不支持的格式一律返回 false
+func VerifyPassword(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash[len("{SHA}"):]), []byte(expected)) == 1
+	}
+	return false
+}
+
+// verifyArgon2id 校验 PHC 格式的 argon2id 哈希：$argon2id$v=19$m=65536,t=3,p=4$salt$hash
+func verifyArgon2id(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+	actual := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(actual, expected) == 1
+}
+
+// ParseHtpasswd 解析 htpasswd 文件（每行 用户名:哈希，# 开头为注释）
+func ParseHtpasswd(r io.Reader) ([]User, error) {
+	var users []User
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("第 %d 行格式错误，应为 用户名:哈希", lineNo)
+		}
+		name, hash := line[:idx], line[idx+1:]
+		if !SupportedHash(hash) {
+			return nil, fmt.Errorf("第 %d 行用户 %s 的哈希格式不受支持（仅支持 bcrypt/argon2id/{SHA}，可用 htpasswd -B 生成）", lineNo, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("第 %d 行用户 %s 重复", lineNo, name)
+		}
+		seen[name] = true
+		users = append(users, User{Username: name, PasswordHash: hash})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
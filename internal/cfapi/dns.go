@@ -2,7 +2,9 @@ package cfapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	cf "github.com/cloudflare/cloudflare-go/v6"
 	"github.com/cloudflare/cloudflare-go/v6/dns"
@@ -26,6 +28,93 @@ func (c *Client) CreateCNAME(ctx context.Context, zoneID, name, target string) (
 	return record.ID, nil
 }
 
+// CreateDNSRecord 按原样创建 A、AAAA 或 CNAME 记录（用于恢复被覆盖的记录）
+func (c *Client) CreateDNSRecord(ctx context.Context, zoneID string, r DNSRecord) (string, error) {
+	ttl := dns.TTL(r.TTL)
+	if ttl == 0 {
+		ttl = 1
+	}
+	var body dns.RecordNewParamsBodyUnion
+	switch r.Type {
+	case "A":
+		body = dns.ARecordParam{Name: cf.F(r.Name), Content: cf.F(r.Content), Type: cf.F(dns.ARecordTypeA), TTL: cf.F(ttl), Proxied: cf.F(r.Proxied)}
+	case "AAAA":
+		body = dns.AAAARecordParam{Name: cf.F(r.Name), Content: cf.F(r.Content), Type: cf.F(dns.AAAARecordTypeAAAA), TTL: cf.F(ttl), Proxied: cf.F(r.Proxied)}
+	case "CNAME":
+		body = dns.CNAMERecordParam{Name: cf.F(r.Name), Content: cf.F(r.Content), Type: cf.F(dns.CNAMERecordTypeCNAME), TTL: cf.F(ttl), Proxied: cf.F(r.Proxied)}
+	default:
+		return "", fmt.Errorf("不支持的记录类型 %s", r.Type)
+	}
+	record, err := c.api.DNS.Records.New(ctx, dns.RecordNewParams{
+		ZoneID: cf.F(zoneID),
+		Body:   body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("创建 %s 记录失败: %w", r.Type, err)
+	}
+	return record.ID, nil
+}
+
+// DNSRecord 简化的 DNS 记录信息
+type DNSRecord struct {
+	ID      string
+	Name    string
+	Type    string
+	Content string
+	Proxied bool
+	TTL     int
+}
+
+func newDNSRecord(r *dns.RecordResponse) DNSRecord {
+	return DNSRecord{ID: r.ID, Name: r.Name, Type: string(r.Type), Content: r.Content, Proxied: r.Proxied, TTL: int(r.TTL)}
+}
+
+// GetDNSRecord 按 ID 读取 DNS 记录，记录不存在时返回 nil
+func (c *Client) GetDNSRecord(ctx context.Context, zoneID, recordID string) (*DNSRecord, error) {
+	record, err := c.api.DNS.Records.Get(ctx, recordID, dns.RecordGetParams{
+		ZoneID: cf.F(zoneID),
+	})
+	if err != nil {
+		var apiErr *cf.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取 DNS 记录失败: %w", err)
+	}
+	r := newDNSRecord(record)
+	return &r, nil
+}
+
+// FindDNSRecords 列出 Zone 中与域名完全匹配的所有记录
+func (c *Client) FindDNSRecords(ctx context.Context, zoneID, name string) ([]DNSRecord, error) {
+	return c.listDNSRecords(ctx, dns.RecordListParams{
+		ZoneID: cf.F(zoneID),
+		Name:   cf.F(dns.RecordListParamsName{Exact: cf.F(name)}),
+	})
+}
+
+// ListCNAMEsTo 列出 Zone 中指向 target 的所有 CNAME 记录
+func (c *Client) ListCNAMEsTo(ctx context.Context, zoneID, target string) ([]DNSRecord, error) {
+	return c.listDNSRecords(ctx, dns.RecordListParams{
+		ZoneID:  cf.F(zoneID),
+		Type:    cf.F(dns.RecordListParamsTypeCNAME),
+		Content: cf.F(dns.RecordListParamsContent{Exact: cf.F(target)}),
+	})
+}
+
+func (c *Client) listDNSRecords(ctx context.Context, params dns.RecordListParams) ([]DNSRecord, error) {
+	pager := c.api.DNS.Records.ListAutoPaging(ctx, params)
+	var result []DNSRecord
+	for pager.Next() {
+		r := pager.Current()
+		result = append(result, newDNSRecord(&r))
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("列出 DNS 记录失败: %w", err)
+	}
+	return result, nil
+}
+
 // DeleteDNSRecord 删除 DNS 记录
 func (c *Client) DeleteDNSRecord(ctx context.Context, zoneID, recordID string) error {
 	_, err := c.api.DNS.Records.Delete(ctx, recordID, dns.RecordDeleteParams{
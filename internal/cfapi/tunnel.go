@@ -53,10 +53,17 @@ func (c *Client) PushIngressConfig(ctx context.Context, tunnelID string, routes
 	// 添加 catch-all 规则
 	ingress := make([]zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngress, 0, len(routes)+1)
 	for _, r := range routes {
-		ingress = append(ingress, zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngress{
+		rule := zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngress{
 			Hostname: cf.F(r.Hostname),
 			Service:  cf.F(r.Service),
-		})
+		}
+		if r.Path != "" {
+			rule.Path = cf.F(r.Path)
+		}
+		if r.Origin != (OriginRequest{}) {
+			rule.OriginRequest = cf.F(r.Origin.params())
+		}
+		ingress = append(ingress, rule)
 	}
 	ingress = append(ingress, zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngress{
 		Service: cf.F("http_status:404"),
@@ -74,10 +81,81 @@ func (c *Client) PushIngressConfig(ctx context.Context, tunnelID string, routes
 	return nil
 }
 
+// GetIngressConfig 读取远端当前的 ingress 规则（不含末尾的 catch-all 规则）
+func (c *Client) GetIngressConfig(ctx context.Context, tunnelID string) ([]IngressRule, error) {
+	res, err := c.api.ZeroTrust.Tunnels.Cloudflared.Configurations.Get(ctx, tunnelID, zero_trust.TunnelCloudflaredConfigurationGetParams{
+		AccountID: cf.F(c.accountID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取 ingress 配置失败: %w", err)
+	}
+	var rules []IngressRule
+	for _, r := range res.Config.Ingress {
+		if r.Hostname == "" {
+			continue
+		}
+		o := r.OriginRequest
+		rules = append(rules, IngressRule{Hostname: r.Hostname, Path: r.Path, Service: r.Service, Origin: OriginRequest{
+			NoTLSVerify:            o.NoTLSVerify,
+			CAPool:                 o.CAPool,
+			OriginServerName:       o.OriginServerName,
+			HTTPHostHeader:         o.HTTPHostHeader,
+			ConnectTimeout:         o.ConnectTimeout,
+			KeepAliveTimeout:       o.KeepAliveTimeout,
+			HTTP2Origin:            o.HTTP2Origin,
+			DisableChunkedEncoding: o.DisableChunkedEncoding,
+		}})
+	}
+	return rules, nil
+}
+
 // IngressRule ingress 路由规则
 type IngressRule struct {
 	Hostname string
+	Path     string // 路径正则，为空匹配全部路径
 	Service  string
+	Origin   OriginRequest
+}
+
+// OriginRequest cloudflared 连接源站的选项，零值字段不下发
+type OriginRequest struct {
+	NoTLSVerify            bool
+	CAPool                 string
+	OriginServerName       string
+	HTTPHostHeader         string
+	ConnectTimeout         int64 // 秒
+	KeepAliveTimeout       int64 // 秒
+	HTTP2Origin            bool
+	DisableChunkedEncoding bool
+}
+
+func (o OriginRequest) params() zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngressOriginRequest {
+	var p zero_trust.TunnelCloudflaredConfigurationUpdateParamsConfigIngressOriginRequest
+	if o.NoTLSVerify {
+		p.NoTLSVerify = cf.F(true)
+	}
+	if o.CAPool != "" {
+		p.CAPool = cf.F(o.CAPool)
+	}
+	if o.OriginServerName != "" {
+		p.OriginServerName = cf.F(o.OriginServerName)
+	}
+	if o.HTTPHostHeader != "" {
+		p.HTTPHostHeader = cf.F(o.HTTPHostHeader)
+	}
+	if o.ConnectTimeout > 0 {
+		p.ConnectTimeout = cf.F(o.ConnectTimeout)
+	}
+	if o.KeepAliveTimeout > 0 {
+		p.KeepAliveTimeout = cf.F(o.KeepAliveTimeout)
+	}
+	if o.HTTP2Origin {
+		p.HTTP2Origin = cf.F(true)
+	}
+	if o.DisableChunkedEncoding {
+		p.DisableChunkedEncoding = cf.F(true)
+	}
+	return p
 }
 
 // GetTunnelToken 获取隧道运行 Token
@@ -3,7 +3,9 @@
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +17,12 @@ type Config struct {
 	Routes      []RouteConfig     `yaml:"routes"`
 	Relay       RelayConfig       `yaml:"relay,omitempty"`
 	Cloudflared CloudflaredConfig `yaml:"cloudflared"`
+
+	// Profiles 命名 profile，各自独立的账户、隧道与路由；顶层的 auth/tunnel/routes 为默认 profile
+	Profiles map[string]*Profile `yaml:"profiles,omitempty"`
+
+	profile string        // 当前视图对应的 profile，空为默认
+	others  []RouteConfig // 其他 profile 的路由，分配代理端口时避开
 }
 
 type AuthConfig struct {
@@ -29,19 +37,155 @@ type TunnelConfig struct {
 }
 
 type RouteConfig struct {
-	Name        string     `yaml:"name"`
-	Hostname    string     `yaml:"hostname"`
-	Service     string     `yaml:"service"`
-	ZoneID      string     `yaml:"zone_id"`
-	DNSRecordID string     `yaml:"dns_record_id"`
-	Auth        *AuthProxy `yaml:"auth,omitempty"`
+	Name        string        `yaml:"name"`
+	Hostname    string        `yaml:"hostname"`
+	Path        string        `yaml:"path,omitempty"` // 路径正则，同一域名可按路径分流到多个服务
+	Service     string        `yaml:"service"`
+	ZoneID      string        `yaml:"zone_id"`
+	DNSRecordID string        `yaml:"dns_record_id"`
+	Auth        *AuthProxy    `yaml:"auth,omitempty"`
+	AllowIPs    []string      `yaml:"allow_ips,omitempty"` // 只允许这些 CIDR 访问
+	DenyIPs     []string      `yaml:"deny_ips,omitempty"`  // 拒绝这些 CIDR 访问（优先于 allow_ips）
+	Origin      *OriginConfig `yaml:"origin,omitempty"`
+	ProxyPort   int           `yaml:"proxy_port,omitempty"` // 鉴权代理固定监听的本地端口，自动分配
+	PrevDNS     []DNSBackup   `yaml:"prev_dns,omitempty"`   // 被 --overwrite 替换的原 DNS 记录，删除路由时恢复
+}
+
+// DNSBackup 添加路由时被覆盖的原 DNS 记录
+type DNSBackup struct {
+	Type    string `yaml:"type"`
+	Content string `yaml:"content"`
+	Proxied bool   `yaml:"proxied,omitempty"`
+	TTL     int    `yaml:"ttl,omitempty"`
+}
+
+// OriginConfig 连接源站的选项（对应 cloudflared originRequest），
+// 受保护路由由鉴权代理按 TLS 与 Host 选项连接 service，其余选项仍下发给 cloudflared
+type OriginConfig struct {
+	NoTLSVerify            bool   `yaml:"no_tls_verify,omitempty"`            // 不校验源站证书（自签名 HTTPS）
+	CAPool                 string `yaml:"ca_pool,omitempty"`                  // 额外信任的 CA 证书文件（PEM）
+	OriginServerName       string `yaml:"origin_server_name,omitempty"`       // TLS SNI 与证书校验使用的主机名
+	HTTPHostHeader         string `yaml:"http_host_header,omitempty"`         // 改写发往源站的 Host 头
+	ConnectTimeout         int    `yaml:"connect_timeout,omitempty"`          // 连接源站超时（秒）
+	KeepAliveTimeout       int    `yaml:"keep_alive_timeout,omitempty"`       // 空闲连接保持时间（秒）
+	HTTP2Origin            bool   `yaml:"http2_origin,omitempty"`             // 使用 HTTP/2 连接源站
+	DisableChunkedEncoding bool   `yaml:"disable_chunked_encoding,omitempty"` // 禁用分块传输编码
 }
 
 type AuthProxy struct {
-	Username   string `yaml:"username"`
-	Password   string `yaml:"password"`
-	SigningKey string `yaml:"signing_key,omitempty"`
-	CookieTTL  int    `yaml:"cookie_ttl,omitempty"`
+	Mode string `yaml:"mode,omitempty"` // password（默认）/ oidc / access
+	// Username/Password 为旧版单用户明文配置，仅为兼容保留，新增用户写入 Users
+	Username   string        `yaml:"username,omitempty"`
+	Password   string        `yaml:"password,omitempty"`
+	Users      []AuthUser    `yaml:"users,omitempty"`
+	SigningKey string        `yaml:"signing_key,omitempty"`
+	CookieTTL  int           `yaml:"cookie_ttl,omitempty"`
+	OIDC       *OIDCConfig   `yaml:"oidc,omitempty"`
+	Access     *AccessConfig `yaml:"access,omitempty"`
+
+	// RetiredKeys 轮换下来的旧签名密钥，过期前仍可校验已签发的 Cookie
+	RetiredKeys []AuthRetiredKey `yaml:"retired_keys,omitempty"`
+
+	// PublicPaths 无需登录即可访问的路径，如 /webhook/*、/healthz
+	PublicPaths []string `yaml:"public_paths,omitempty"`
+	// PublicWebSocketPaths 无需登录即可建立 WebSocket 的路径，如 /socket.io/*
+	PublicWebSocketPaths []string `yaml:"public_websocket_paths,omitempty"`
+
+	// APIKeys 机器客户端密钥，APIKeyHeader 为空时通过 Authorization: Bearer 传递
+	APIKeys      []AuthAPIKey `yaml:"api_keys,omitempty"`
+	APIKeyHeader string       `yaml:"api_key_header,omitempty"`
+
+	// IdentityHeaders 转发给上游的身份头，未配置时使用默认头名
+	IdentityHeaders *IdentityHeaders `yaml:"identity_headers,omitempty"`
+	// LoginPage 登录页定制，未配置时使用内置页面
+	LoginPage *LoginPage `yaml:"login_page,omitempty"`
+}
+
+// LoginPage 自定义登录页：html/template 模板文件、标题与 Logo（图片 URL 或本地文件）
+type LoginPage struct {
+	Template string `yaml:"template,omitempty"`
+	Title    string `yaml:"title,omitempty"`
+	Logo     string `yaml:"logo,omitempty"`
+}
+
+// IdentityHeaders 身份头名称，留空使用默认值，设为 - 表示不发送
+type IdentityHeaders struct {
+	User   string `yaml:"user,omitempty"`   // 默认 X-Forwarded-User
+	Email  string `yaml:"email,omitempty"`  // 默认 X-Forwarded-Email
+	Groups string `yaml:"groups,omitempty"` // 默认 X-Auth-Request-Groups
+}
+
+// AuthRetiredKey 已轮换的签名密钥（hex）及其失效时间
+type AuthRetiredKey struct {
+	Key     string    `yaml:"key"`
+	Expires time.Time `yaml:"expires"`
+}
+
+// AuthAPIKey 命名 API Key，只保存 SHA-256 哈希
+type AuthAPIKey struct {
+	Name    string    `yaml:"name"`
+	Hash    string    `yaml:"hash"`
+	Created time.Time `yaml:"created"`
+}
+
+// OIDCConfig OpenID Connect 登录配置，至少需要一个允许列表
+type OIDCConfig struct {
+	Issuer         string   `yaml:"issuer"`
+	ClientID       string   `yaml:"client_id"`
+	ClientSecret   string   `yaml:"client_secret,omitempty"`
+	RedirectURL    string   `yaml:"redirect_url,omitempty"`
+	Scopes         []string `yaml:"scopes,omitempty"`
+	GroupsClaim    string   `yaml:"groups_claim,omitempty"`
+	AllowedEmails  []string `yaml:"allowed_emails,omitempty"`
+	AllowedDomains []string `yaml:"allowed_domains,omitempty"`
+	AllowedGroups  []string `yaml:"allowed_groups,omitempty"`
+}
+
+// AccessConfig Cloudflare Access 校验配置
+type AccessConfig struct {
+	TeamDomain string `yaml:"team_domain"` // 如 myteam.cloudflareaccess.com
+	Audience   string `yaml:"audience"`    // Access 应用的 AUD 标签
+}
+
+// AuthUser 鉴权用户，密码只保存哈希（bcrypt/argon2id/{SHA}）
+type AuthUser struct {
+	Username     string `yaml:"username"`
+	PasswordHash string `yaml:"password_hash"`
+	TOTPSecret   string `yaml:"totp_secret,omitempty"`
+}
+
+func (a *AuthProxy) FindUser(username string) *AuthUser {
+	for i := range a.Users {
+		if a.Users[i].Username == username { return &a.Users[i] }
+	}
+	return nil
+}
+
+func (a *AuthProxy) RemoveUser(username string) bool {
+	for i, u := range a.Users {
+		if u.Username == username {
+			a.Users = append(a.Users[:i], a.Users[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AuthProxy) FindAPIKey(name string) *AuthAPIKey {
+	for i := range a.APIKeys {
+		if a.APIKeys[i].Name == name { return &a.APIKeys[i] }
+	}
+	return nil
+}
+
+func (a *AuthProxy) RemoveAPIKey(name string) bool {
+	for i, k := range a.APIKeys {
+		if k.Name == name {
+			a.APIKeys = append(a.APIKeys[:i], a.APIKeys[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
 
 func (a *AuthProxy) CookieTTLOrDefault() int {
@@ -102,7 +246,22 @@ func Path() string {
 	return filepath.Join(Dir(), "config.yml")
 }
 
+// SessionPath 返回路由鉴权会话的持久化文件路径，命名 profile 使用独立的子目录
+func SessionPath(route string) string {
+	return filepath.Join(Dir(), sessionsDir, activeProfile, route+".json")
+}
+
+// Load 读取当前 profile 的配置
 func Load() (*Config, error) {
+	cfg, err := loadFile()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.view(activeProfile), nil
+}
+
+// loadFile 读取整个配置文件，不区分 profile
+func loadFile() (*Config, error) {
 	data, err := os.ReadFile(Path())
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -119,6 +278,9 @@ func Load() (*Config, error) {
 }
 
 func (c *Config) Save() error {
+	if c.profile != "" {
+		return c.saveProfile()
+	}
 	// 即使是在当前目录，也确保路径合法（虽然通常 exe 目录肯定存在）
 	data, err := yaml.Marshal(c)
 	if err != nil {
@@ -135,6 +297,13 @@ func (c *Config) FindRoute(name string) *RouteConfig {
 	return nil
 }
 
+// FindRouteByHostname 返回使用该域名的第一条路由（同一域名的路由共用一条 DNS 记录）
+func (c *Config) FindRouteByHostname(hostname string) *RouteConfig {
+	for i := range c.Routes {
+		if strings.EqualFold(c.Routes[i].Hostname, hostname) { return &c.Routes[i] }
+	}
+	return nil
+}
 func (c *Config) RemoveRoute(name string) bool {
 	for i, r := range c.Routes {
 		if r.Name == name {
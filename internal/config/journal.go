@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qingchencloud/cftunnel/internal/cfapi"
+	"gopkg.in/yaml.v3"
+)
+
+// 操作步骤
+const (
+	StepCreateRecord = "create_record" // 创建 DNS 记录，完成后回填 After 中该域名的记录 ID
+	StepDeleteRecord = "delete_record" // 删除 DNS 记录，Record 保存删除前的内容用于撤销
+	StepDeleteTunnel = "delete_tunnel" // 删除隧道，无法撤销
+	StepSaveConfig   = "save_config"   // 保存 After 为本地配置
+	StepPushIngress  = "push_ingress"  // 按 After 推送 ingress 配置
+	StepSetIngress   = "set_ingress"   // 推送 Ingress 中的规则，撤销时推送 PrevIngress（逐条确认的 sync 修复）
+)
+
+// 步骤状态，空表示未开始
+const (
+	StepStarted = "started" // 已开始但未确认完成（可能执行到一半进程退出）
+	StepDone    = "done"
+)
+
+// JournalStep 操作中的一步副作用
+type JournalStep struct {
+	Action   string     `yaml:"action"`
+	Status   string     `yaml:"status,omitempty"`
+	ZoneID   string     `yaml:"zone_id,omitempty"`
+	Hostname string     `yaml:"hostname,omitempty"`
+	RecordID string     `yaml:"record_id,omitempty"`
+	Record   *DNSBackup `yaml:"record,omitempty"`
+	TunnelID string     `yaml:"tunnel_id,omitempty"`
+
+	Ingress     []cfapi.IngressRule `yaml:"ingress,omitempty"`
+	PrevIngress []cfapi.IngressRule `yaml:"prev_ingress,omitempty"`
+}
+
+// Journal 进行中的操作日志。每一步执行前后都会写盘，失败时据此自动回滚，
+// 进程中途退出后由 cftunnel recover 完成或撤销
+type Journal struct {
+	Op      string        `yaml:"op"`
+	Target  string        `yaml:"target,omitempty"` // 路由名或隧道名
+	Started time.Time     `yaml:"started"`
+	Before  *Config       `yaml:"before"` // 操作前的配置，撤销时恢复
+	After   *Config       `yaml:"after"`  // 操作完成后的配置
+	Steps   []JournalStep `yaml:"steps"`
+}
+
+// JournalPath 返回当前 profile 操作日志的文件路径
+func JournalPath() string {
+	return filepath.Join(Dir(), journalPrefix+ProfileSuffix()+journalExt)
+}
+
+// LoadJournal 读取未完成的操作日志，没有时返回 nil
+func LoadJournal() (*Journal, error) {
+	data, err := os.ReadFile(JournalPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var j Journal
+	if err := yaml.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	for _, c := range []*Config{j.Before, j.After} {
+		if c != nil {
+			c.profile = activeProfile
+		}
+	}
+	return &j, nil
+}
+
+func (j *Journal) Save() error {
+	data, err := yaml.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(JournalPath(), data, 0600)
+}
+
+// RemoveJournal 操作完成或撤销后删除日志
+func RemoveJournal() error {
+	if err := os.Remove(JournalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Clone 深拷贝配置
+func (c *Config) Clone() (*Config, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var cp Config
+	if err := yaml.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	cp.profile, cp.others = c.profile, c.others
+	return &cp, nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile 顶层 auth/tunnel/routes 对应的 profile 名称
+const DefaultProfile = "default"
+
+// Profile 命名 profile：独立的账户凭据、隧道与路由
+type Profile struct {
+	Auth   AuthConfig    `yaml:"auth"`
+	Tunnel TunnelConfig  `yaml:"tunnel"`
+	Routes []RouteConfig `yaml:"routes"`
+}
+
+var activeProfile string
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SetProfile 切换当前 profile，之后的 Load、SessionPath、JournalPath 以及 PID 文件都作用于该 profile
+func SetProfile(name string) error {
+	if name == DefaultProfile {
+		name = ""
+	}
+	if name != "" && !profileNameRe.MatchString(name) {
+		return fmt.Errorf("profile 名称只能包含字母、数字、- 和 _")
+	}
+	activeProfile = name
+	return nil
+}
+
+// ProfileName 返回当前 profile 名称
+func ProfileName() string {
+	if activeProfile == "" {
+		return DefaultProfile
+	}
+	return activeProfile
+}
+
+// ProfileSuffix 用于区分各 profile 的文件名（PID、操作日志），默认 profile 为空
+func ProfileSuffix() string {
+	return profileSuffix(activeProfile)
+}
+
+func profileSuffix(name string) string {
+	if name == "" || name == DefaultProfile {
+		return ""
+	}
+	return "-" + name
+}
+
+// 各 profile 独立的本地文件名为 <前缀><ProfileSuffix><扩展名>，会话保存在 sessions/<profile>/ 下
+const (
+	pidPrefix     = "cloudflared"
+	pidExt        = ".pid"
+	journalPrefix = "journal"
+	journalExt    = ".yml"
+	sessionsDir   = "sessions"
+)
+
+// PIDPath 返回当前 profile 的 cloudflared PID 文件路径，每个 profile 各自运行一个 cloudflared
+func PIDPath() string {
+	return filepath.Join(Dir(), pidPrefix+ProfileSuffix()+pidExt)
+}
+
+// ProfileFiles 返回命名 profile 专属的本地文件：PID、操作日志与会话目录
+func ProfileFiles(name string) []string {
+	dir := Dir()
+	return []string{
+		filepath.Join(dir, pidPrefix+profileSuffix(name)+pidExt),
+		filepath.Join(dir, journalPrefix+profileSuffix(name)+journalExt),
+		filepath.Join(dir, sessionsDir, name),
+	}
+}
+
+// DataFiles 返回配置目录中的全部数据文件：配置文件、会话目录，以及所有 profile
+// （包括已删除 profile 遗留）的 PID 与操作日志
+func DataFiles() []string {
+	dir := Dir()
+	files := []string{Path(), filepath.Join(dir, sessionsDir)}
+	for _, f := range [][2]string{{pidPrefix, pidExt}, {journalPrefix, journalExt}} {
+		files = append(files, filepath.Join(dir, f[0]+f[1]))
+		named, _ := filepath.Glob(filepath.Join(dir, f[0]+"-*"+f[1]))
+		files = append(files, named...)
+	}
+	return files
+}
+
+// ProfileNames 返回配置中的所有 profile，默认 profile 排在最前
+func ProfileNames() ([]string, error) {
+	cfg, err := loadFile()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// RemoveProfile 从配置文件中删除命名 profile
+func RemoveProfile(name string) error {
+	cfg, err := loadFile()
+	if err != nil {
+		return err
+	}
+	if cfg.Profiles[name] == nil {
+		return fmt.Errorf("profile %s 不存在", name)
+	}
+	delete(cfg.Profiles, name)
+	return cfg.Save()
+}
+
+// OtherRoutes 返回其他 profile 的路由
+func (c *Config) OtherRoutes() []RouteConfig {
+	return c.others
+}
+
+// view 返回 profile 的配置视图：命名 profile 的 auth/tunnel/routes 映射到顶层字段，
+// 其他命令无需感知 profile。不存在的 profile 返回空视图，保存时自动创建
+func (c *Config) view(name string) *Config {
+	var others []RouteConfig
+	for n, p := range c.Profiles {
+		if n != name {
+			others = append(others, p.Routes...)
+		}
+	}
+	if name == "" {
+		c.others = others
+		return c
+	}
+	v := *c
+	v.profile = name
+	v.others = append(others, c.Routes...)
+	v.Auth, v.Tunnel, v.Routes = AuthConfig{}, TunnelConfig{}, nil
+	if p := c.Profiles[name]; p != nil {
+		v.Auth, v.Tunnel, v.Routes = p.Auth, p.Tunnel, p.Routes
+	}
+	return &v
+}
+
+// saveProfile 重新读取配置文件，只替换本 profile 的内容（relay 与 cloudflared 为全局设置）
+func (c *Config) saveProfile() error {
+	base, err := loadFile()
+	if err != nil {
+		return err
+	}
+	if base.Profiles == nil {
+		base.Profiles = make(map[string]*Profile)
+	}
+	base.Profiles[c.profile] = &Profile{Auth: c.Auth, Tunnel: c.Tunnel, Routes: c.Routes}
+	base.Relay, base.Cloudflared = c.Relay, c.Cloudflared
+	return base.Save()
+}
@@ -13,10 +13,9 @@ import (
 	"github.com/qingchencloud/cftunnel/internal/config"
 )
 
-// pidFilePath 返回 cloudflared PID 文件路径
+// pidFilePath 返回当前 profile 的 cloudflared PID 文件路径，每个 profile 各自运行一个 cloudflared
 func pidFilePath() string {
-	// 确保使用 config.Dir() 获取的绝对路径
-	return filepath.Join(config.Dir(), "cloudflared.pid")
+	return config.PIDPath()
 }
 
 // Start 启动 cloudflared
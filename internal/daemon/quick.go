@@ -83,7 +83,7 @@ func extractURL(line string) string {
 }
 
 // StartQuickWithAuth 启动带鉴权代理的免域名模式
-func StartQuickWithAuth(port, username, password string) error {
+func StartQuickWithAuth(port string, users []authproxy.User) error {
 	binPath, err := EnsureCloudflared()
 	if err != nil {
 		return err
@@ -94,10 +94,9 @@ func StartQuickWithAuth(port, username, password string) error {
 
 	// 启动鉴权代理
 	proxy, err := authproxy.New(authproxy.Config{
-		Username:   username,
-		Password:   password,
-		TargetPort: port,
-		SigningKey:  authproxy.RandomKey(),
+		Users:      users,
+		Upstream:   "http://127.0.0.1:" + port,
+		SigningKey: authproxy.RandomKey(),
 		CookieTTL:  24 * time.Hour,
 	})
 	if err != nil {